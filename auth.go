@@ -0,0 +1,253 @@
+package gofed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// ErrUnauthorized is returned by resolvers guarded by the authorization
+// directives when the request doesn't satisfy them
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authenticated requires the request to be authenticated
+func Authenticated() *DirectiveValue {
+	return &DirectiveValue{Name: AuthenticatedDirective}
+}
+
+// RequiresScopes requires the request to hold every scope of at least one of the given scope sets
+func RequiresScopes(scopes ...[]string) *DirectiveValue {
+	return &DirectiveValue{
+		Name: RequiresScopesDirective,
+		Values: map[string]interface{}{
+			"scopes": scopes,
+		},
+	}
+}
+
+// Policy requires the request to satisfy every policy of at least one of the given policy sets
+func Policy(policies ...[]string) *DirectiveValue {
+	return &DirectiveValue{
+		Name: PolicyDirective,
+		Values: map[string]interface{}{
+			"policies": policies,
+		},
+	}
+}
+
+// AuthInfo describes what the current request is allowed to access, it is
+// checked against the authorization directives when enforcement is enabled
+type AuthInfo struct {
+	Authenticated bool
+	Scopes        []string
+	Policies      []string
+}
+
+type authInfoKey struct{}
+
+// WithAuthInfo returns a copy of ctx carrying the request's authorization info
+func WithAuthInfo(ctx context.Context, info *AuthInfo) context.Context {
+	return context.WithValue(ctx, authInfoKey{}, info)
+}
+
+// AuthInfoFromContext returns the authorization info stored with WithAuthInfo, or nil
+func AuthInfoFromContext(ctx context.Context) *AuthInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(authInfoKey{}).(*AuthInfo)
+	return info
+}
+
+// SetAuthorizationEnforcement enables checking @authenticated, @requiresScopes
+// and @policy in the subgraph itself, against the AuthInfo of the request
+// context. It must be called before BuildSubgraphSchema.
+func (f *Federation) SetAuthorizationEnforcement(enabled bool) {
+	f.enforceAuthorization = enabled
+}
+
+// authRequirement holds the authorization directives guarding a field
+type authRequirement struct {
+	authenticated bool
+	scopes        [][][]string
+	policies      [][][]string
+}
+
+func (r *authRequirement) add(directives []*DirectiveValue) {
+	for _, d := range directives {
+		switch d.Name {
+		case AuthenticatedDirective:
+			r.authenticated = true
+		case RequiresScopesDirective:
+			r.scopes = append(r.scopes, stringSets(d.Values["scopes"]))
+		case PolicyDirective:
+			r.policies = append(r.policies, stringSets(d.Values["policies"]))
+		}
+	}
+}
+
+func (r *authRequirement) empty() bool {
+	return !r.authenticated && len(r.scopes) == 0 && len(r.policies) == 0
+}
+
+// check reports an error unless info satisfies every directive of the requirement
+func (r *authRequirement) check(info *AuthInfo) error {
+	if info == nil {
+		return ErrUnauthorized
+	}
+	if r.authenticated && !info.Authenticated {
+		return fmt.Errorf("%w: not authenticated", ErrUnauthorized)
+	}
+	for _, sets := range r.scopes {
+		if !satisfiesAny(sets, info.Scopes) {
+			return fmt.Errorf("%w: missing required scopes", ErrUnauthorized)
+		}
+	}
+	for _, sets := range r.policies {
+		if !satisfiesAny(sets, info.Policies) {
+			return fmt.Errorf("%w: missing required policies", ErrUnauthorized)
+		}
+	}
+	return nil
+}
+
+// satisfiesAny reports if granted contains every value of at least one set
+func satisfiesAny(sets [][]string, granted []string) bool {
+	for _, set := range sets {
+		ok := true
+		for _, v := range set {
+			if !containsString(granted, v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stringSets normalises a directive argument holding a list of string lists
+func stringSets(v interface{}) [][]string {
+	switch v := v.(type) {
+	case [][]string:
+		return v
+	case []interface{}:
+		sets := make([][]string, 0, len(v))
+		for _, item := range v {
+			set := make([]string, 0)
+			switch item := item.(type) {
+			case []string:
+				set = append(set, item...)
+			case []interface{}:
+				for _, s := range item {
+					if s, ok := s.(string); ok {
+						set = append(set, s)
+					}
+				}
+			}
+			sets = append(sets, set)
+		}
+		return sets
+	default:
+		return nil
+	}
+}
+
+// applyAuthorization wraps the resolver of every field guarded by an
+// authorization directive on the field, its parent type or its return type.
+// Directives on an interface, or an interface field, guard the fields of the
+// objects implementing it.
+func (f *Federation) applyAuthorization() error {
+	for _, t := range sortTypeMap(f.schema.TypeMap()) {
+		obj, ok := t.(*graphql.Object)
+		if !ok || strings.HasPrefix(obj.Name(), "__") {
+			continue
+		}
+
+		parentDirectives, err := getDirectives(obj.Extensions())
+		if err != nil {
+			return fmt.Errorf("%s: %s", obj.Name(), err)
+		}
+		interfaceDirectives := make([][]*DirectiveValue, 0, len(obj.Interfaces()))
+		for _, iface := range obj.Interfaces() {
+			directives, err := getDirectives(iface.Extensions())
+			if err != nil {
+				return fmt.Errorf("%s: %s", iface.Name(), err)
+			}
+			interfaceDirectives = append(interfaceDirectives, directives)
+		}
+
+		for _, field := range sortFields(obj.Fields()) {
+			requirement := &authRequirement{}
+			requirement.add(parentDirectives)
+			for _, directives := range interfaceDirectives {
+				requirement.add(directives)
+			}
+
+			for _, iface := range obj.Interfaces() {
+				ifaceField, ok := iface.Fields()[field.Name]
+				if !ok {
+					continue
+				}
+				ifaceFieldDirectives, err := getDirectives(ifaceField.Extensions)
+				if err != nil {
+					return fmt.Errorf("%s.%s: %s", iface.Name(), field.Name, err)
+				}
+				requirement.add(ifaceFieldDirectives)
+			}
+
+			fieldDirectives, err := getDirectives(field.Extensions)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %s", obj.Name(), field.Name, err)
+			}
+			requirement.add(fieldDirectives)
+
+			typeDirectives, err := getDirectives(namedTypeExtensions(field.Type))
+			if err != nil {
+				return fmt.Errorf("%s.%s: %s", obj.Name(), field.Name, err)
+			}
+			requirement.add(typeDirectives)
+
+			if requirement.empty() {
+				continue
+			}
+			field.Resolve = authorizeResolver(requirement, field.Resolve)
+		}
+	}
+	return nil
+}
+
+// authorizeResolver guards resolve with the given requirement
+func authorizeResolver(requirement *authRequirement, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	if resolve == nil {
+		resolve = graphql.DefaultResolveFn
+	}
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if err := requirement.check(AuthInfoFromContext(p.Context)); err != nil {
+			return nil, err
+		}
+		return resolve(p)
+	}
+}
+
+// namedTypeExtensions returns the extensions of the named type wrapped by t
+func namedTypeExtensions(t graphql.Type) map[string]interface{} {
+	switch t := graphql.GetNamed(t).(type) {
+	case *graphql.Object:
+		return t.Extensions()
+	case *graphql.Interface:
+		return t.Extensions()
+	case *graphql.Union:
+		return t.Extensions()
+	case *graphql.Enum:
+		return t.Extensions()
+	case *graphql.Scalar:
+		return t.Extensions()
+	}
+	return nil
+}
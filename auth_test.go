@@ -0,0 +1,233 @@
+package gofed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildAuthFederation(enforce bool) *Federation {
+
+	var employeeType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Employee",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+				"email": &graphql.Field{
+					Type:       graphql.String,
+					Extensions: Directives(Authenticated()),
+				},
+				"salary": &graphql.Field{
+					Type:       graphql.Int,
+					Extensions: Directives(RequiresScopes([]string{"read:hr"}, []string{"admin"})),
+				},
+			},
+			Extensions: mediaKey(),
+		},
+	)
+
+	fed := NewFederation()
+	fed.SetAuthorizationEnforcement(enforce)
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"employee": &graphql.Field{
+			Type: employeeType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1", "email": "bilbo@shire.me", "salary": 10}, nil
+			},
+		},
+	}, nil)
+
+	return fed
+}
+
+func TestAuthDirectivesSDL(t *testing.T) {
+
+	fed := buildAuthFederation(false)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`  @link(url: "https://specs.apollo.dev/federation/v2.5", import: ["@authenticated", "@key", "@requiresScopes"])`,
+		`  email: String @authenticated`,
+		`  salary: Int @requiresScopes(scopes: [["read:hr"], ["admin"]])`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+}
+
+func TestAuthEnforcement(t *testing.T) {
+
+	fed := buildAuthFederation(true)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	run := func(info *AuthInfo) string {
+		ctx := context.Background()
+		if info != nil {
+			ctx = WithAuthInfo(ctx, info)
+		}
+		r := graphql.Do(graphql.Params{
+			Schema:        *fed.Schema(),
+			RequestString: `{ employee { id, email, salary } }`,
+			Context:       ctx,
+		})
+		rJSON, _ := json.Marshal(r.Data)
+		return string(rJSON)
+	}
+
+	tests := []struct {
+		info     *AuthInfo
+		expected string
+	}{
+		{nil, `{"employee":{"email":null,"id":"1","salary":null}}`},
+		{&AuthInfo{Authenticated: true}, `{"employee":{"email":"bilbo@shire.me","id":"1","salary":null}}`},
+		{&AuthInfo{Authenticated: true, Scopes: []string{"read:hr"}}, `{"employee":{"email":"bilbo@shire.me","id":"1","salary":10}}`},
+		{&AuthInfo{Scopes: []string{"admin"}}, `{"employee":{"email":null,"id":"1","salary":10}}`},
+	}
+	for i, test := range tests {
+		if result := run(test.info); result != test.expected {
+			fmt.Fprintln(os.Stdout, result)
+			t.Errorf("test %d: unexpected result", i)
+		}
+	}
+}
+
+func TestAuthEnforcementKeepsTypes(t *testing.T) {
+
+	employeeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Employee",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"email": &graphql.Field{
+				Type:       graphql.String,
+				Extensions: Directives(Authenticated()),
+			},
+		},
+		Extensions: Directives(&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "id"}}),
+	})
+	build := func(enforce bool) *Federation {
+		fed := NewFederation()
+		fed.SetAuthorizationEnforcement(enforce)
+		fed.BuildSubgraphSchema(graphql.Fields{
+			"employee": &graphql.Field{
+				Type: employeeType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return map[string]interface{}{"id": "1", "email": "bilbo@shire.me"}, nil
+				},
+			},
+		}, nil)
+		if err := fed.Error(); err != nil {
+			t.Fatalf("unexpected build error: %s", err)
+		}
+		return fed
+	}
+	run := func(fed *Federation, info *AuthInfo) string {
+		r := graphql.Do(graphql.Params{
+			Schema:        *fed.Schema(),
+			RequestString: `{ employee { email } }`,
+			Context:       WithAuthInfo(context.Background(), info),
+		})
+		rJSON, _ := json.Marshal(r.Data)
+		return string(rJSON)
+	}
+
+	// the enforcing subgraphs don't leak their wrappers into the types they share
+	enforced := build(true)
+	enforcedAgain := build(true)
+	open := build(false)
+	if employeeType.Fields()["email"].Resolve != nil {
+		t.Error("building with enforcement changed the resolver of Employee.email")
+	}
+	for _, fed := range []*Federation{enforced, enforcedAgain} {
+		if result := run(fed, &AuthInfo{}); result != `{"employee":{"email":null}}` {
+			t.Errorf("unexpected enforced result: %s", result)
+		}
+		if result := run(fed, &AuthInfo{Authenticated: true}); result != `{"employee":{"email":"bilbo@shire.me"}}` {
+			t.Errorf("unexpected authenticated result: %s", result)
+		}
+	}
+	if result := run(open, &AuthInfo{}); result != `{"employee":{"email":"bilbo@shire.me"}}` {
+		t.Errorf("unexpected result without enforcement: %s", result)
+	}
+}
+
+func TestAuthEnforcementInterfaces(t *testing.T) {
+
+	personInterface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Person",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"phone": &graphql.Field{
+				Type:       graphql.String,
+				Extensions: Directives(RequiresScopes([]string{"admin"})),
+			},
+		},
+		Extensions: Directives(Authenticated()),
+	})
+	employeeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Employee",
+		Interfaces: []*graphql.Interface{personInterface},
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"phone": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	fed := NewFederation()
+	fed.SetAuthorizationEnforcement(true)
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"employee": &graphql.Field{
+			Type: employeeType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"name": "Bilbo", "phone": "555"}, nil
+			},
+		},
+	}, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	// the directives of Person and Person.phone guard the fields of Employee
+	tests := []struct {
+		info     *AuthInfo
+		expected string
+	}{
+		{nil, `{"employee":{"name":null,"phone":null}}`},
+		{&AuthInfo{Authenticated: true}, `{"employee":{"name":"Bilbo","phone":null}}`},
+		{&AuthInfo{Authenticated: true, Scopes: []string{"admin"}}, `{"employee":{"name":"Bilbo","phone":"555"}}`},
+	}
+	for i, test := range tests {
+		ctx := context.Background()
+		if test.info != nil {
+			ctx = WithAuthInfo(ctx, test.info)
+		}
+		r := graphql.Do(graphql.Params{
+			Schema:        *fed.Schema(),
+			RequestString: `{ employee { name phone } }`,
+			Context:       ctx,
+		})
+		if data, _ := json.Marshal(r.Data); string(data) != test.expected {
+			t.Errorf("test %d: unexpected result %s", i, data)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Command gofed provides tooling for gofed subgraph schemas.
+//
+// Usage:
+//
+//	gofed migrate [-o file] schema.graphql
+//	gofed generate [-package name] [-o file] schema.graphql...
+//
+// migrate converts a Federation 1 subgraph SDL into the equivalent Federation
+// 2 SDL. The schema is read from stdin when the file is "-" or missing, and
+// notes about anything that needs a manual review are printed to stderr.
+//
+// generate writes the Go source of a subgraph from federated SDL files, read
+// as a single document, so it fits a go:generate directive:
+//
+//	//go:generate go run github.com/jesse-apollo/gofed/cmd/gofed generate -package products -o schema_gen.go schema.graphql
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jesse-apollo/gofed"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gofed <command> [arguments]\n\n")
+	fmt.Fprintf(os.Stderr, "commands:\n")
+	fmt.Fprintf(os.Stderr, "  migrate   convert a federation 1 subgraph sdl to federation 2\n")
+	fmt.Fprintf(os.Stderr, "  generate  generate the go source of a subgraph from its sdl\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "migrate":
+		err = migrate(os.Args[2:])
+	case "generate":
+		err = generate(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "gofed: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gofed: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	output := flags.String("o", "", "write the federation 2 sdl to `file` instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gofed migrate [-o file] schema.graphql\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	sdl, err := readSchema(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	migrated, notes, err := gofed.MigrateSDL(sdl)
+	if err != nil {
+		return err
+	}
+	for _, note := range notes {
+		fmt.Fprintf(os.Stderr, "note: %s\n", note)
+	}
+
+	if *output == "" {
+		_, err = io.WriteString(os.Stdout, migrated)
+		return err
+	}
+	return os.WriteFile(*output, []byte(migrated), 0644)
+}
+
+func generate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	packageName := flags.String("package", "", "package `name` of the generated source, defaults to $GOPACKAGE")
+	output := flags.String("o", "", "write the go source to `file` instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gofed generate [-package name] [-o file] schema.graphql...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *packageName == "" {
+		*packageName = os.Getenv("GOPACKAGE")
+	}
+	if *packageName == "" {
+		return fmt.Errorf("generate: -package is required outside of go generate")
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	schemas := make([]string, 0, len(files))
+	for _, file := range files {
+		sdl, err := readSchema(file)
+		if err != nil {
+			return err
+		}
+		schemas = append(schemas, sdl)
+	}
+
+	source, err := gofed.GenerateGo(strings.Join(schemas, "\n"), *packageName)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(source)
+		return err
+	}
+	return os.WriteFile(*output, source, 0644)
+}
+
+func readSchema(path string) (string, error) {
+	if path == "" || path == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		return string(b), err
+	}
+	b, err := os.ReadFile(path)
+	return string(b), err
+}
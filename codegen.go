@@ -0,0 +1,917 @@
+package gofed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// GenerateGo generates the Go source of a subgraph from a federated SDL
+// document, so the Go schema can't drift from the SDL. The source declares,
+// in package packageName:
+//
+//   - a model struct per object and input type, a string type per enum and a
+//     Go interface per interface and union type
+//   - a resolver interface for the root types and for the fields taking
+//     arguments, and a reference resolver interface per resolvable entity
+//     with a key struct holding the fields of its first resolvable @key
+//   - a BuildSubgraphSchema function creating the graphql-go types, carrying
+//     the federation directives as DirectiveValue extensions, binding a
+//     Resolvers value and building the subgraph schema on a Federation
+//
+// The document is built and validated like BuildSubgraphSchemaFromSDL first,
+// its errors are returned.
+func GenerateGo(sdl, packageName string) ([]byte, error) {
+	doc, err := parseSDL(sdl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.newFederation().Error(); err != nil {
+		return nil, err
+	}
+
+	g := &generator{doc: doc, types: doc.sortedTypes()}
+	g.generate(packageName)
+	source, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated source: %s", err)
+	}
+	return source, nil
+}
+
+// DecodeArguments decodes the arguments of a field into the struct v with
+// encoding/json, generated resolvers use it to build their argument structs
+func DecodeArguments(args map[string]interface{}, v interface{}) error {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// LiteralValue returns the Go value of a GraphQL literal, generated custom
+// scalars use it to parse literals
+func LiteralValue(value ast.Value) interface{} {
+	return valueFromAST(value)
+}
+
+// generator writes the Go source of an sdlDocument
+type generator struct {
+	buf   bytes.Buffer
+	doc   *sdlDocument
+	types []graphql.Type
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// entityKey is a resolvable entity and the key its reference resolver decodes
+type entityKey struct {
+	name   string
+	fields graphql.FieldDefinitionMap
+	key    string
+	goType string
+}
+
+// argsField is a field taking arguments, which gets an argument struct
+type argsField struct {
+	parent string
+	field  *graphql.FieldDefinition
+}
+
+func (g *generator) generate(packageName string) {
+	roots := g.rootFields()
+	argFields := g.argumentFields()
+	entities := g.entities()
+
+	g.printf("// Code generated by gofed generate. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", packageName)
+	g.printf("import (\n")
+	if len(roots) > 0 || len(argFields) > 0 || len(entities) > 0 {
+		g.printf("\t\"context\"\n")
+	}
+	if len(argFields) > 0 {
+		g.printf("\t\"fmt\"\n")
+	}
+	g.printf("\n")
+	g.printf("\t\"github.com/graphql-go/graphql\"\n")
+	g.printf("\t\"github.com/jesse-apollo/gofed\"\n")
+	g.printf(")\n\n")
+
+	g.models()
+	for _, e := range entities {
+		g.keyStruct(e)
+	}
+	for _, root := range roots {
+		for _, field := range root.fields {
+			if len(field.Args) > 0 {
+				g.argsStruct(root.name, field)
+			}
+		}
+	}
+	for _, f := range argFields {
+		g.argsStruct(f.parent, f.field)
+	}
+	g.resolverInterfaces(roots, argFields, entities)
+	g.buildFunction(roots, entities)
+}
+
+// rootType is the Query or Mutation type with its fields, sorted by name
+type rootType struct {
+	name   string
+	fields []*graphql.FieldDefinition
+}
+
+func (g *generator) rootFields() []rootType {
+	roots := make([]rootType, 0, 2)
+	for _, root := range []struct {
+		name   string
+		fields graphql.Fields
+	}{{"Query", g.doc.queryFields}, {"Mutation", g.doc.mutationFields}} {
+		// a throwaway object turns the field configs into definitions, the
+		// federation fields added by BuildSubgraphSchema are left out
+		fields := make(graphql.Fields, len(root.fields))
+		for name, field := range root.fields {
+			if name != "_entities" && name != "_service" {
+				fields[name] = field
+			}
+		}
+		obj := graphql.NewObject(graphql.ObjectConfig{Name: root.name, Fields: fields})
+		if len(fields) > 0 {
+			roots = append(roots, rootType{name: root.name, fields: sortFields(obj.Fields())})
+		}
+	}
+	return roots
+}
+
+// argumentFields returns the fields of object types taking arguments
+func (g *generator) argumentFields() []argsField {
+	fields := make([]argsField, 0)
+	for _, t := range g.types {
+		obj, ok := t.(*graphql.Object)
+		if !ok {
+			continue
+		}
+		for _, field := range sortFields(obj.Fields()) {
+			if len(field.Args) > 0 {
+				fields = append(fields, argsField{parent: obj.Name(), field: field})
+			}
+		}
+	}
+	return fields
+}
+
+// entities returns the entity objects and interfaces with a resolvable @key
+func (g *generator) entities() []entityKey {
+	entities := make([]entityKey, 0)
+	for _, t := range g.types {
+		var fields graphql.FieldDefinitionMap
+		var extensions map[string]interface{}
+		goType := ""
+		switch t := t.(type) {
+		case *graphql.Object:
+			fields, extensions, goType = t.Fields(), t.Extensions(), "*"+goName(t.Name())
+		case *graphql.Interface:
+			fields, extensions, goType = t.Fields(), t.Extensions(), goName(t.Name())
+		default:
+			continue
+		}
+		keys, err := resolvableKeyDirectives(extensions)
+		if err != nil || len(keys) == 0 {
+			continue
+		}
+		entities = append(entities, entityKey{name: t.Name(), fields: fields, key: keys[0], goType: goType})
+	}
+	return entities
+}
+
+// models writes the Go types values of the schema types are held in
+func (g *generator) models() {
+	// the interfaces and unions each object belongs to
+	memberOf := make(map[string][]string)
+	for _, t := range g.types {
+		switch t := t.(type) {
+		case *graphql.Object:
+			for _, iface := range t.Interfaces() {
+				memberOf[t.Name()] = append(memberOf[t.Name()], iface.Name())
+			}
+		case *graphql.Union:
+			for _, member := range t.Types() {
+				memberOf[member.Name()] = append(memberOf[member.Name()], t.Name())
+			}
+		}
+	}
+
+	for _, t := range g.types {
+		name := goName(t.Name())
+		switch t := t.(type) {
+		case *graphql.Enum:
+			g.comment(t.Description(), name+" is the "+t.Name()+" enum")
+			g.printf("type %s string\n\n", name)
+			g.printf("const (\n")
+			for _, v := range sortEnumValues(t.Values()) {
+				g.printf("\t%s %s = %q\n", enumConstName(t.Name(), v.Name), name, v.Name)
+			}
+			g.printf(")\n\n")
+		case *graphql.Interface:
+			g.comment(t.Description(), name+" is implemented by the models of the "+t.Name()+" interface")
+			g.printf("type %s interface {\n\tIs%s()\n}\n\n", name, name)
+		case *graphql.Union:
+			g.comment(t.Description(), name+" is implemented by the models of the "+t.Name()+" union members")
+			g.printf("type %s interface {\n\tIs%s()\n}\n\n", name, name)
+		case *graphql.Object:
+			g.comment(t.PrivateDescription, name+" is the model of the "+t.Name()+" type")
+			g.printf("type %s struct {\n", name)
+			for _, field := range sortFields(t.Fields()) {
+				if len(field.Args) > 0 {
+					continue
+				}
+				g.structField(field.Name, field.Type)
+			}
+			g.printf("}\n\n")
+			parents := memberOf[t.Name()]
+			sort.Strings(parents)
+			for _, parent := range parents {
+				g.printf("func (%s) Is%s() {}\n\n", name, goName(parent))
+			}
+		case *graphql.InputObject:
+			g.comment(t.Description(), name+" is the model of the "+t.Name()+" input type")
+			g.printf("type %s struct {\n", name)
+			for _, field := range sortInputFields(t.Fields()) {
+				g.structField(field.Name(), field.Type)
+			}
+			g.printf("}\n\n")
+		}
+	}
+}
+
+// keyStruct writes the struct the reference resolver of an entity decodes
+// representations into
+func (g *generator) keyStruct(e entityKey) {
+	name := goName(e.name) + "Key"
+	g.printf("// %s holds the fields of the @key(fields: %q) of %s\n", name, e.key, e.name)
+	g.printf("type %s struct {\n", name)
+	for _, fieldName := range topLevelKeyFields(e.key) {
+		if field, ok := e.fields[fieldName]; ok {
+			g.structField(fieldName, field.Type)
+		}
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) argsStruct(parent string, field *graphql.FieldDefinition) {
+	name := argsStructName(parent, field.Name)
+	g.printf("// %s holds the arguments of %s.%s\n", name, parent, field.Name)
+	g.printf("type %s struct {\n", name)
+	args := append([]*graphql.Argument(nil), field.Args...)
+	sort.Slice(args, func(i, j int) bool {
+		return args[i].Name() < args[j].Name()
+	})
+	for _, arg := range args {
+		g.structField(arg.Name(), arg.Type)
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) structField(name string, t graphql.Type) {
+	g.printf("\t%s %s `json:\"%s\"`\n", goName(name), goTypeRef(t), name)
+}
+
+func (g *generator) resolverInterfaces(roots []rootType, argFields []argsField, entities []entityKey) {
+	for _, root := range roots {
+		g.printf("// %sResolver resolves the fields of %s\n", root.name, root.name)
+		g.printf("type %sResolver interface {\n", root.name)
+		for _, field := range root.fields {
+			g.printf("\t%s\n", resolverMethod(root.name, field, false))
+		}
+		g.printf("}\n\n")
+	}
+
+	for i := 0; i < len(argFields); {
+		parent := argFields[i].parent
+		g.printf("// %sResolver resolves the fields of %s taking arguments\n", goName(parent), parent)
+		g.printf("type %sResolver interface {\n", goName(parent))
+		for ; i < len(argFields) && argFields[i].parent == parent; i++ {
+			g.printf("\t%s\n", resolverMethod(parent, argFields[i].field, true))
+		}
+		g.printf("}\n\n")
+	}
+
+	for _, e := range entities {
+		name := goName(e.name)
+		g.printf("// %sReferenceResolver resolves %s entities from their keys, returning an\n", name, e.name)
+		g.printf("// entity, or nil when it isn't found, for each key in order\n")
+		g.printf("type %sReferenceResolver interface {\n", name)
+		g.printf("\tResolve%sReferences(ctx context.Context, keys []%sKey) ([]%s, error)\n", name, name, e.goType)
+		g.printf("}\n\n")
+	}
+
+	g.printf("// Resolvers holds the resolvers bound by BuildSubgraphSchema\n")
+	g.printf("type Resolvers struct {\n")
+	for _, root := range roots {
+		g.printf("\t%s %sResolver\n", root.name, root.name)
+	}
+	for i, f := range argFields {
+		if i == 0 || argFields[i-1].parent != f.parent {
+			g.printf("\t%s %sResolver\n", goName(f.parent), goName(f.parent))
+		}
+	}
+	for _, e := range entities {
+		g.printf("\t%sReference %sReferenceResolver\n", goName(e.name), goName(e.name))
+	}
+	g.printf("}\n\n")
+}
+
+// resolverMethod returns the method resolving field in a resolver interface
+func resolverMethod(parent string, field *graphql.FieldDefinition, hasSource bool) string {
+	params := []string{"ctx context.Context"}
+	if hasSource {
+		params = append(params, "obj *"+goName(parent))
+	}
+	if len(field.Args) > 0 {
+		params = append(params, "args "+argsStructName(parent, field.Name))
+	}
+	return fmt.Sprintf("%s(%s) (%s, error)", goName(field.Name), strings.Join(params, ", "), goTypeRef(field.Type))
+}
+
+func argsStructName(parent, field string) string {
+	return goName(parent) + goName(field) + "Args"
+}
+
+// declarations writes the graphql-go variables of the named types and custom
+// directives, they are declared first as object fields reference each other
+func (g *generator) declarations() {
+	g.printf("var (\n")
+	for _, t := range g.types {
+		g.printf("\t%s %s\n", typeVarName(t.Name()), graphqlTypeName(t))
+	}
+	for _, d := range g.doc.directives {
+		g.printf("\t%s *graphql.Directive\n", directiveVarName(d.Name))
+	}
+	g.printf(")\n")
+
+	// types referenced outside of thunks are created first
+	order := []func(graphql.Type) bool{
+		func(t graphql.Type) bool { _, ok := t.(*graphql.Scalar); return ok },
+		func(t graphql.Type) bool { _, ok := t.(*graphql.Enum); return ok },
+		func(t graphql.Type) bool { _, ok := t.(*graphql.InputObject); return ok },
+		func(t graphql.Type) bool { _, ok := t.(*graphql.Interface); return ok },
+		func(t graphql.Type) bool { _, ok := t.(*graphql.Object); return ok },
+		func(t graphql.Type) bool { _, ok := t.(*graphql.Union); return ok },
+	}
+	for _, matches := range order {
+		for _, t := range g.types {
+			if matches(t) {
+				g.declaration(t)
+			}
+		}
+	}
+	for _, d := range g.doc.directives {
+		g.directive(d)
+	}
+}
+
+func (g *generator) declaration(t graphql.Type) {
+	name := typeVarName(t.Name())
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		g.printf("%s = graphql.NewScalar(graphql.ScalarConfig{\n", name)
+		g.printf("Name: %q,\n", t.Name())
+		g.description(t.Description())
+		g.extensions(t.Extensions())
+		g.printf("Serialize: func(value interface{}) interface{} { return value },\n")
+		g.printf("ParseValue: func(value interface{}) interface{} { return value },\n")
+		g.printf("ParseLiteral: gofed.LiteralValue,\n")
+		g.printf("})\n")
+	case *graphql.Enum:
+		g.printf("%s = graphql.NewEnum(graphql.EnumConfig{\n", name)
+		g.printf("Name: %q,\n", t.Name())
+		g.description(t.Description())
+		g.extensions(t.Extensions())
+		g.printf("Values: graphql.EnumValueConfigMap{\n")
+		for _, v := range sortEnumValues(t.Values()) {
+			g.printf("%q: {\n", v.Name)
+			g.printf("Value: %s,\n", enumConstName(t.Name(), v.Name))
+			g.description(v.Description)
+			g.deprecation(v.DeprecationReason)
+			g.extensions(v.Extensions)
+			g.printf("},\n")
+		}
+		g.printf("},\n")
+		g.printf("})\n")
+	case *graphql.InputObject:
+		g.printf("%s = graphql.NewInputObject(graphql.InputObjectConfig{\n", name)
+		g.printf("Name: %q,\n", t.Name())
+		g.description(t.Description())
+		g.extensions(t.Extensions())
+		g.printf("Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {\n")
+		g.printf("return graphql.InputObjectConfigFieldMap{\n")
+		for _, field := range sortInputFields(t.Fields()) {
+			g.printf("%q: {\n", field.Name())
+			g.printf("Type: %s,\n", typeExpr(field.Type))
+			g.defaultValue(field.Type, field.DefaultValue)
+			g.description(field.PrivateDescription)
+			g.extensions(field.Extensions)
+			g.printf("},\n")
+		}
+		g.printf("}\n")
+		g.printf("}),\n")
+		g.printf("})\n")
+	case *graphql.Interface:
+		g.printf("%s = graphql.NewInterface(graphql.InterfaceConfig{\n", name)
+		g.printf("Name: %q,\n", t.Name())
+		g.description(t.Description())
+		g.extensions(t.Extensions())
+		g.fieldsThunk(t.Name(), t.Fields(), false)
+		g.printf("})\n")
+	case *graphql.Object:
+		g.printf("%s = graphql.NewObject(graphql.ObjectConfig{\n", name)
+		g.printf("Name: %q,\n", t.Name())
+		g.description(t.PrivateDescription)
+		g.extensions(t.Extensions())
+		if interfaces := t.Interfaces(); len(interfaces) > 0 {
+			names := make([]string, 0, len(interfaces))
+			for _, iface := range interfaces {
+				names = append(names, typeVarName(iface.Name()))
+			}
+			g.printf("Interfaces: []*graphql.Interface{%s},\n", strings.Join(names, ", "))
+		}
+		g.fieldsThunk(t.Name(), t.Fields(), true)
+		g.printf("IsTypeOf: func(p graphql.IsTypeOfParams) bool {\n")
+		g.printf("switch p.Value.(type) {\n")
+		g.printf("case %s, *%s:\n", goName(t.Name()), goName(t.Name()))
+		g.printf("return true\n")
+		g.printf("}\n")
+		g.printf("return false\n")
+		g.printf("},\n")
+		g.printf("})\n")
+	case *graphql.Union:
+		g.printf("%s = graphql.NewUnion(graphql.UnionConfig{\n", name)
+		g.printf("Name: %q,\n", t.Name())
+		g.description(t.Description())
+		g.extensions(t.Extensions())
+		members := make([]string, 0, len(t.Types()))
+		for _, member := range t.Types() {
+			members = append(members, typeVarName(member.Name()))
+		}
+		g.printf("Types: []*graphql.Object{%s},\n", strings.Join(members, ", "))
+		g.printf("})\n")
+	}
+}
+
+// fieldsThunk writes the fields of an interface or object, the object fields
+// taking arguments resolve through their resolver interface
+func (g *generator) fieldsThunk(parent string, fields graphql.FieldDefinitionMap, resolvers bool) {
+	g.printf("Fields: graphql.FieldsThunk(func() graphql.Fields {\n")
+	g.printf("return graphql.Fields{\n")
+	for _, field := range sortFields(fields) {
+		resolve := ""
+		if resolvers && len(field.Args) > 0 {
+			resolve = resolveFunc(parent, field, true)
+		}
+		g.field(field, resolve)
+	}
+	g.printf("}\n")
+	g.printf("}),\n")
+}
+
+// field writes the config of a field, with resolve as its Resolve function
+func (g *generator) field(field *graphql.FieldDefinition, resolve string) {
+	g.printf("%q: &graphql.Field{\n", field.Name)
+	g.printf("Type: %s,\n", typeExpr(field.Type))
+	g.description(field.Description)
+	g.deprecation(field.DeprecationReason)
+	if len(field.Args) > 0 {
+		g.printf("Args: graphql.FieldConfigArgument{\n")
+		args := append([]*graphql.Argument(nil), field.Args...)
+		sort.Slice(args, func(i, j int) bool {
+			return args[i].Name() < args[j].Name()
+		})
+		for _, arg := range args {
+			g.printf("%q: &graphql.ArgumentConfig{\n", arg.Name())
+			g.printf("Type: %s,\n", typeExpr(arg.Type))
+			g.defaultValue(arg.Type, arg.DefaultValue)
+			g.description(arg.PrivateDescription)
+			g.extensions(arg.Extensions)
+			g.printf("},\n")
+		}
+		g.printf("},\n")
+	}
+	g.extensions(field.Extensions)
+	if resolve != "" {
+		g.printf("Resolve: %s,\n", resolve)
+	}
+	g.printf("},\n")
+}
+
+func (g *generator) directive(d *graphql.Directive) {
+	g.printf("%s = graphql.NewDirective(graphql.DirectiveConfig{\n", directiveVarName(d.Name))
+	g.printf("Name: %q,\n", d.Name)
+	g.description(d.Description)
+	locations := make([]string, 0, len(d.Locations))
+	for _, location := range d.Locations {
+		locations = append(locations, strconv.Quote(location))
+	}
+	g.printf("Locations: []string{%s},\n", strings.Join(locations, ", "))
+	if len(d.Args) > 0 {
+		g.printf("Args: graphql.FieldConfigArgument{\n")
+		for _, arg := range d.Args {
+			g.printf("%q: &graphql.ArgumentConfig{\n", arg.Name())
+			g.printf("Type: %s,\n", typeExpr(arg.Type))
+			g.defaultValue(arg.Type, arg.DefaultValue)
+			g.description(arg.PrivateDescription)
+			g.printf("},\n")
+		}
+		g.printf("},\n")
+	}
+	g.printf("})\n")
+}
+
+func (g *generator) buildFunction(roots []rootType, entities []entityKey) {
+	g.printf("// BuildSubgraphSchema declares the custom directives and types of the schema on fed,\n")
+	g.printf("// binds the resolvers in r and builds the subgraph schema. The graphql-go\n")
+	g.printf("// types are created on every call, each schema keeps its own resolvers.\n")
+	g.printf("func BuildSubgraphSchema(fed *gofed.Federation, r Resolvers) *graphql.Schema {\n")
+	g.declarations()
+	for _, d := range g.doc.directives {
+		if g.doc.repeatable[d.Name] {
+			g.printf("fed.AddRepeatableDirective(%s)\n", directiveVarName(d.Name))
+		} else {
+			g.printf("fed.AddDirective(%s)\n", directiveVarName(d.Name))
+		}
+	}
+	for _, c := range g.doc.composed {
+		g.printf("fed.ComposeDirective(%q, %q)\n", c.name, c.specURL)
+	}
+
+	types := make([]string, 0, len(g.types))
+	for _, t := range g.types {
+		types = append(types, typeVarName(t.Name()))
+	}
+	g.printf("fed.AddTypes(%s)\n", strings.Join(types, ", "))
+
+	for _, e := range entities {
+		name := goName(e.name)
+		g.printf("if r.%sReference != nil {\n", name)
+		g.printf("gofed.RegisterEntityResolver(fed, %q, r.%sReference.Resolve%sReferences)\n", e.name, name, name)
+		g.printf("}\n")
+	}
+
+	fieldsVar := map[string]string{"Query": "nil", "Mutation": "nil"}
+	for _, root := range roots {
+		variable := strings.ToLower(root.name) + "Fields"
+		fieldsVar[root.name] = variable
+		g.printf("%s := graphql.Fields{\n", variable)
+		for _, field := range root.fields {
+			g.field(field, resolveFunc(root.name, field, false))
+		}
+		g.printf("}\n")
+	}
+	g.printf("return fed.BuildSubgraphSchema(%s, %s)\n", fieldsVar["Query"], fieldsVar["Mutation"])
+	g.printf("}\n")
+}
+
+// resolveFunc returns a graphql-go resolve function calling the resolver
+// method of field on the Resolvers value r
+func resolveFunc(parent string, field *graphql.FieldDefinition, hasSource bool) string {
+	var b strings.Builder
+	b.WriteString("func(p graphql.ResolveParams) (interface{}, error) {\n")
+	params := []string{"p.Context"}
+	if hasSource {
+		fmt.Fprintf(&b, "obj, ok := p.Source.(*%s)\n", goName(parent))
+		b.WriteString("if !ok {\n")
+		fmt.Fprintf(&b, "return nil, fmt.Errorf(\"%s.%s: source is %%T, not *%s\", p.Source)\n", parent, field.Name, goName(parent))
+		b.WriteString("}\n")
+		params = append(params, "obj")
+	}
+	if len(field.Args) > 0 {
+		fmt.Fprintf(&b, "var args %s\n", argsStructName(parent, field.Name))
+		b.WriteString("if err := gofed.DecodeArguments(p.Args, &args); err != nil {\n")
+		b.WriteString("return nil, err\n")
+		b.WriteString("}\n")
+		params = append(params, "args")
+	}
+	fmt.Fprintf(&b, "return r.%s.%s(%s)\n", goName(parent), goName(field.Name), strings.Join(params, ", "))
+	b.WriteString("}")
+	return b.String()
+}
+
+func (g *generator) comment(description, fallback string) {
+	if description == "" {
+		description = fallback
+	}
+	for _, line := range strings.Split(description, "\n") {
+		g.printf("// %s\n", strings.TrimSpace(line))
+	}
+}
+
+func (g *generator) description(description string) {
+	if description != "" {
+		g.printf("Description: %q,\n", description)
+	}
+}
+
+func (g *generator) deprecation(reason string) {
+	if reason != "" {
+		g.printf("DeprecationReason: %q,\n", reason)
+	}
+}
+
+func (g *generator) defaultValue(t graphql.Input, value interface{}) {
+	if value != nil {
+		g.printf("DefaultValue: %s,\n", goDefault(t, value))
+	}
+}
+
+// extensions writes the directives of an extensions map as gofed.Directives
+func (g *generator) extensions(extensions map[string]interface{}) {
+	directives, err := getDirectives(extensions)
+	if err != nil || len(directives) == 0 {
+		return
+	}
+	values := make([]string, 0, len(directives))
+	for _, d := range directives {
+		values = append(values, directiveExpr(d))
+	}
+	g.printf("Extensions: gofed.Directives(%s),\n", strings.Join(values, ", "))
+}
+
+// directiveExpr returns the Go expression of a directive, using the typed
+// constructor of federation directives when its arguments fit
+func directiveExpr(d *DirectiveValue) string {
+	fields, hasFields := d.Values["fields"].(string)
+	switch {
+	case len(d.Values) == 0:
+		constructors := map[string]string{
+			ShareableDirective:       "Shareable",
+			InaccessibleDirective:    "Inaccessible",
+			ExternalDirective:        "External",
+			ExtendsDirective:         "Extends",
+			InterfaceObjectDirective: "InterfaceObject",
+			AuthenticatedDirective:   "Authenticated",
+		}
+		if constructor, ok := constructors[d.Name]; ok {
+			return "gofed." + constructor + "()"
+		}
+		return fmt.Sprintf("&gofed.DirectiveValue{Name: %q}", d.Name)
+	case d.Name == KeyDirective && hasFields && len(d.Values) == 1:
+		return fmt.Sprintf("gofed.Key(%q)", fields)
+	case d.Name == KeyDirective && hasFields && len(d.Values) == 2:
+		if resolvable, ok := d.Values["resolvable"].(bool); ok {
+			return fmt.Sprintf("gofed.Key(%q, gofed.Resolvable(%t))", fields, resolvable)
+		}
+	case d.Name == RequiresDirective && hasFields && len(d.Values) == 1:
+		return fmt.Sprintf("gofed.Requires(%q)", fields)
+	case d.Name == ProvidesDirective && hasFields && len(d.Values) == 1:
+		return fmt.Sprintf("gofed.Provides(%q)", fields)
+	case d.Name == TagDirective && len(d.Values) == 1:
+		if name, ok := d.Values["name"].(string); ok {
+			return fmt.Sprintf("gofed.Tag(%q)", name)
+		}
+	case d.Name == OverrideDirective:
+		from, ok := d.Values["from"].(string)
+		label, hasLabel := d.Values["label"].(string)
+		if ok && len(d.Values) == 1 {
+			return fmt.Sprintf("gofed.Override(%q)", from)
+		}
+		if ok && hasLabel && len(d.Values) == 2 {
+			return fmt.Sprintf("gofed.OverrideLabel(%q, %q)", from, label)
+		}
+	}
+	return fmt.Sprintf("&gofed.DirectiveValue{Name: %q, Values: %s}", d.Name, goLiteral(d.Values))
+}
+
+// goDefault returns the Go expression of a default value of type t, enum
+// values are their generated constants
+func goDefault(t graphql.Input, value interface{}) string {
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return goDefault(t.OfType.(graphql.Input), value)
+	case *graphql.List:
+		values, ok := value.([]interface{})
+		if !ok {
+			return goDefault(t.OfType.(graphql.Input), value)
+		}
+		items := make([]string, 0, len(values))
+		for _, item := range values {
+			items = append(items, goDefault(t.OfType.(graphql.Input), item))
+		}
+		return "[]interface{}{" + strings.Join(items, ", ") + "}"
+	case *graphql.InputObject:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		fields := t.Fields()
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(values))
+		for _, k := range keys {
+			item := goLiteral(values[k])
+			if field, ok := fields[k]; ok {
+				item = goDefault(field.Type, values[k])
+			}
+			items = append(items, strconv.Quote(k)+": "+item)
+		}
+		return "map[string]interface{}{" + strings.Join(items, ", ") + "}"
+	case *graphql.Enum:
+		if name, ok := value.(string); ok {
+			return enumConstName(t.Name(), name)
+		}
+	}
+	return goLiteral(value)
+}
+
+// goLiteral returns the Go expression of a value parsed from SDL
+func goLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEIN") {
+			s = "float64(" + s + ")"
+		}
+		return s
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, goLiteral(item))
+		}
+		return "[]interface{}{" + strings.Join(items, ", ") + "}"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(v))
+		for _, k := range keys {
+			items = append(items, strconv.Quote(k)+": "+goLiteral(v[k]))
+		}
+		return "map[string]interface{}{" + strings.Join(items, ", ") + "}"
+	}
+	return fmt.Sprintf("%#v", value)
+}
+
+// typeExpr returns the graphql-go expression of a type reference
+func typeExpr(t graphql.Type) string {
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return "graphql.NewNonNull(" + typeExpr(t.OfType) + ")"
+	case *graphql.List:
+		return "graphql.NewList(" + typeExpr(t.OfType) + ")"
+	}
+	switch t {
+	case graphql.String, graphql.Int, graphql.Float, graphql.Boolean, graphql.ID:
+		return "graphql." + t.Name()
+	}
+	return typeVarName(t.Name())
+}
+
+// goTypeRef returns the Go type holding values of a type reference. Objects
+// are pointers, nullable scalars too, nullable enums use the empty string
+func goTypeRef(t graphql.Type) string {
+	nonNull := false
+	if n, ok := t.(*graphql.NonNull); ok {
+		t, nonNull = n.OfType, true
+	}
+	switch t := t.(type) {
+	case *graphql.List:
+		return "[]" + goTypeRef(t.OfType)
+	case *graphql.Object:
+		return "*" + goName(t.Name())
+	case *graphql.InputObject:
+		return "*" + goName(t.Name())
+	case *graphql.Interface, *graphql.Union, *graphql.Enum:
+		return goName(t.Name())
+	}
+
+	goType := "interface{}"
+	switch t {
+	case graphql.String, graphql.ID:
+		goType = "string"
+	case graphql.Int:
+		goType = "int"
+	case graphql.Float:
+		goType = "float64"
+	case graphql.Boolean:
+		goType = "bool"
+	default:
+		return goType
+	}
+	if !nonNull {
+		return "*" + goType
+	}
+	return goType
+}
+
+func graphqlTypeName(t graphql.Type) string {
+	switch t.(type) {
+	case *graphql.Scalar:
+		return "*graphql.Scalar"
+	case *graphql.Enum:
+		return "*graphql.Enum"
+	case *graphql.InputObject:
+		return "*graphql.InputObject"
+	case *graphql.Interface:
+		return "*graphql.Interface"
+	case *graphql.Union:
+		return "*graphql.Union"
+	}
+	return "*graphql.Object"
+}
+
+func typeVarName(name string) string {
+	return lowerName(goName(name)) + "Type"
+}
+
+func directiveVarName(name string) string {
+	return lowerName(goName(name)) + "Directive"
+}
+
+// lowerName lowers the first word of a Go name, "Product" becomes "product"
+// and "URLPath" "urlPath"
+func lowerName(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	// the last upper case letter starts the next word
+	if n > 1 && n < len(runes) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+func enumConstName(enum, value string) string {
+	return goName(enum) + goName(strings.ToLower(value))
+}
+
+// initialisms are upper cased when they make up a word of a Go name
+var initialisms = map[string]bool{
+	"api": true, "html": true, "http": true, "https": true, "id": true, "ip": true,
+	"json": true, "sku": true, "sql": true, "ttl": true, "uri": true, "url": true, "uuid": true,
+}
+
+// goName returns the exported Go name of a GraphQL name, "userId" becomes
+// "UserID" and "in_stock" "InStock"
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range nameWords(name) {
+		if initialisms[strings.ToLower(word)] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+// nameWords splits a GraphQL name at underscores and lower to upper case changes
+func nameWords(name string) []string {
+	words := make([]string, 0)
+	start := 0
+	runes := []rune(name)
+	for i := 0; i <= len(runes); i++ {
+		split := i == len(runes) || runes[i] == '_' ||
+			(i > start && unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]))
+		if !split {
+			continue
+		}
+		if i > start {
+			words = append(words, string(runes[start:i]))
+		}
+		start = i
+		if i < len(runes) && runes[i] == '_' {
+			start = i + 1
+		}
+	}
+	return words
+}
@@ -0,0 +1,274 @@
+package gofed
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const generateSDL = schemaFirstSDL + `
+interface Media @key(fields: "id") {
+  id: ID!
+  title: String
+}
+
+type Book implements Media @key(fields: "id") {
+  id: ID!
+  title: String
+  price(currency: Unit = CM): Float @deprecated(reason: "use cost")
+}
+
+union SearchResult = Book | Product
+
+input ReviewInput {
+  body: String!
+  rating: Int = 3
+}
+
+type Mutation {
+  addReview(upc: String!, review: ReviewInput!): Boolean
+}
+`
+
+func TestGenerateGo(t *testing.T) {
+
+	source, err := GenerateGo(generateSDL, "products")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "schema.go", source, 0); err != nil {
+		t.Fatalf("generated source doesn't parse: %s\n%s", err, source)
+	}
+
+	for _, snippet := range []string{
+		"// Code generated by gofed generate. DO NOT EDIT.\n\npackage products\n",
+		// models
+		"type Product struct {\n\tDimensions *Dimensions `json:\"dimensions\"`\n\tInStock    *bool       `json:\"inStock\"`\n\tUpc        string      `json:\"upc\"`\n}",
+		"type Book struct {\n\tID    string  `json:\"id\"`\n\tTitle *string `json:\"title\"`\n}",
+		"func (Book) IsMedia() {}",
+		"func (Product) IsSearchResult() {}",
+		"type Unit string",
+		"UnitCm Unit = \"CM\"",
+		"type ReviewInput struct {\n\tBody   string `json:\"body\"`\n\tRating *int   `json:\"rating\"`\n}",
+		// key, argument and resolver types
+		"type ProductKey struct {\n\tUpc string `json:\"upc\"`\n}",
+		"type BookPriceArgs struct {\n\tCurrency Unit `json:\"currency\"`\n}",
+		"TopProducts(ctx context.Context, args QueryTopProductsArgs) ([]*Product, error)",
+		"AddReview(ctx context.Context, args MutationAddReviewArgs) (*bool, error)",
+		"Price(ctx context.Context, obj *Book, args BookPriceArgs) (*float64, error)",
+		"ResolveProductReferences(ctx context.Context, keys []ProductKey) ([]*Product, error)",
+		"ResolveMediaReferences(ctx context.Context, keys []MediaKey) ([]Media, error)",
+		// graphql-go types carrying the directives
+		`Extensions:  gofed.Directives(gofed.Key("upc")),`,
+		`Extensions: gofed.Directives(gofed.Shareable()),`,
+		`Extensions:  gofed.Directives(&gofed.DirectiveValue{Name: "cacheTTL", Values: map[string]interface{}{"seconds": 30}}),`,
+		`DeprecationReason: "use cost",`,
+		"Interfaces: []*graphql.Interface{mediaType},",
+		"Types: []*graphql.Object{bookType, productType},",
+		"DefaultValue: UnitCm,",
+		// schema building
+		`fed.ComposeDirective("cacheTTL", "https://myorg.dev/cache/v1.0")`,
+		`gofed.RegisterEntityResolver(fed, "Product", r.ProductReference.ResolveProductReferences)`,
+		"return r.Book.Price(p.Context, obj, args)",
+		"return fed.BuildSubgraphSchema(queryFields, mutationFields)",
+	} {
+		if !strings.Contains(string(source), snippet) {
+			t.Errorf("generated source is missing %q:\n%s", snippet, source)
+		}
+	}
+
+	// _entities and _service are added by BuildSubgraphSchema, not generated
+	if strings.Contains(string(source), "_entities") {
+		t.Errorf("generated source declares federation fields:\n%s", source)
+	}
+}
+
+func TestGenerateGoTypeChecks(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("type checking gofed and graphql-go from source is slow")
+	}
+	source, err := GenerateGo(generateSDL, "products")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the file is placed in this package's directory so gofed and graphql-go
+	// are imported from this module
+	dir, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(dir, "products", "schema.go"), source, 0)
+	if err != nil {
+		t.Fatalf("generated source doesn't parse: %s", err)
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := config.Check("products", fset, []*ast.File{file}, nil); err != nil {
+		t.Errorf("generated source doesn't type check: %s\n%s", err, source)
+	}
+}
+
+// generatedMain builds the generated schema twice with different Book
+// resolvers and prints the results of a query on each
+const generatedMain = `package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/graphql-go/graphql"
+	"github.com/jesse-apollo/gofed"
+)
+
+type books float64
+
+func (b books) Price(ctx context.Context, obj *Book, args BookPriceArgs) (*float64, error) {
+	price := float64(b)
+	if args.Currency != UnitCm {
+		price *= 2.54
+	}
+	return &price, nil
+}
+
+func (books) ResolveBookReferences(ctx context.Context, keys []BookKey) ([]*Book, error) {
+	entities := make([]*Book, 0, len(keys))
+	for _, key := range keys {
+		entities = append(entities, &Book{ID: key.ID})
+	}
+	return entities, nil
+}
+
+func main() {
+	query := ` + "`" + `{ _entities(representations: [{__typename: "Book", id: "1"}]) { ... on Book { id price } } _service { sdl } }` + "`" + `
+	for _, price := range []books{1, 2} {
+		fed := gofed.NewFederation()
+		schema := BuildSubgraphSchema(fed, Resolvers{Book: price, BookReference: price})
+		if schema == nil {
+			fmt.Fprintln(os.Stderr, fed.Error())
+			os.Exit(1)
+		}
+		json.NewEncoder(os.Stdout).Encode(graphql.Do(graphql.Params{Schema: *schema, RequestString: query}))
+	}
+}
+`
+
+func TestGenerateGoRuns(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("building the generated schema is slow")
+	}
+	source, err := GenerateGo(generateSDL, "main")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the program is built in testdata so gofed and graphql-go are imported
+	// from this module
+	dir, err := os.MkdirTemp("testdata", "generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string][]byte{"schema.go": source, "main.go": []byte(generatedMain)} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running the generated schema failed: %s\n%s", err, output)
+	}
+
+	// each schema resolves Book.price with its own resolvers
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for _, price := range []float64{1, 2} {
+		var result struct {
+			Data struct {
+				Entities []struct {
+					ID    string  `json:"id"`
+					Price float64 `json:"price"`
+				} `json:"_entities"`
+				Service struct {
+					SDL string `json:"sdl"`
+				} `json:"_service"`
+			} `json:"data"`
+			Errors []interface{} `json:"errors"`
+		}
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("unexpected output %s: %s", output, err)
+		}
+		if len(result.Errors) > 0 || len(result.Data.Entities) != 1 || result.Data.Entities[0].Price != price {
+			t.Errorf("unexpected result for price %v: %s", price, output)
+		}
+
+		sdl := result.Data.Service.SDL
+		if line := `  price(currency: Unit = CM): Float @deprecated(reason: "use cost")`; !strings.Contains(sdl, line+"\n") {
+			t.Errorf("_service sdl is missing line %q:\n%s", line, sdl)
+		}
+		if _, err := parseSDL(sdl, nil); err != nil {
+			t.Errorf("_service sdl doesn't parse: %s\n%s", err, sdl)
+		}
+	}
+}
+
+func TestGenerateGoErrors(t *testing.T) {
+
+	tests := []struct {
+		sdl string
+		err string
+	}{
+		{"type Query { me: User }", "Query.me: unknown type User"},
+		{`type User @key(fields: "uid") { id: ID! } type Query { me: User }`, "User.uid: field is not defined on User"},
+	}
+	for _, test := range tests {
+		if _, err := GenerateGo(test.sdl, "users"); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+
+	for name, expected := range map[string]string{
+		"upc":         "Upc",
+		"id":          "ID",
+		"userId":      "UserID",
+		"topProducts": "TopProducts",
+		"in_stock":    "InStock",
+		"cacheTTL":    "CacheTTL",
+		"Product":     "Product",
+	} {
+		if got := goName(name); got != expected {
+			t.Errorf("goName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestDecodeArguments(t *testing.T) {
+
+	var args struct {
+		First    *int   `json:"first"`
+		Currency string `json:"currency"`
+	}
+	if err := DecodeArguments(map[string]interface{}{"first": 5, "currency": "CM"}, &args); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if args.First == nil || *args.First != 5 || args.Currency != "CM" {
+		t.Errorf("unexpected arguments %+v", args)
+	}
+}
@@ -0,0 +1,103 @@
+package gofed
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ComposeDirectiveDirective is the federation directive keeping a custom
+// directive in the supergraph
+const ComposeDirectiveDirective = "composeDirective"
+
+const composeDirectiveVersion = "2.1"
+
+// composedDirective is a custom directive kept in the supergraph through @composeDirective
+type composedDirective struct {
+	name    string
+	specURL string
+}
+
+// ComposeDirective keeps the custom directive named name in the supergraph.
+// The directive must be declared with AddDirective and belong to the spec at
+// specURL, e.g. "https://myorg.dev/myorg/v1.0". Directives named with the spec
+// prefix, like @myorg__cacheTTL, don't need to be imported from the spec.
+func (f *Federation) ComposeDirective(name, specURL string) {
+	f.composed = append(f.composed, &composedDirective{
+		name:    strings.TrimPrefix(name, "@"),
+		specURL: specURL,
+	})
+}
+
+// validateComposedDirectives checks every composed directive is declared and
+// linked to a valid spec
+func (f *Federation) validateComposedDirectives() error {
+	for _, c := range f.composed {
+		declared := false
+		for _, d := range f.directives {
+			if d.Name == c.name {
+				declared = true
+			}
+		}
+		if !declared {
+			return fmt.Errorf("@composeDirective: @%s is not declared with AddDirective", c.name)
+		}
+		if _, ok := federationDirectives[c.name]; ok {
+			return fmt.Errorf("@composeDirective: @%s is a federation directive", c.name)
+		}
+		if _, _, err := parseSpecURL(c.specURL); err != nil {
+			return fmt.Errorf("@composeDirective: @%s: %s", c.name, err)
+		}
+	}
+	return nil
+}
+
+// parseSpecURL splits a spec url like "https://myorg.dev/myorg/v1.0" into
+// the spec name and version
+func parseSpecURL(url string) (string, string, error) {
+	parts := strings.Split(strings.TrimRight(url, "/"), "/")
+	if len(parts) < 2 || !strings.Contains(url, "://") {
+		return "", "", fmt.Errorf("invalid spec url %q", url)
+	}
+	name, version := parts[len(parts)-2], parts[len(parts)-1]
+	if name == "" || len(version) < 2 || version[0] != 'v' || !strings.Contains(version, ".") {
+		return "", "", fmt.Errorf("spec url %q must end with /<name>/v<major>.<minor>", url)
+	}
+	return name, version[1:], nil
+}
+
+// printComposedDirectives writes a @link for each spec the composed directives
+// belong to, followed by their @composeDirective usages
+func printComposedDirectives(composed []*composedDirective, out *strings.Builder) {
+	if len(composed) == 0 {
+		return
+	}
+
+	imports := make(map[string][]string)
+	urls := make([]string, 0, len(composed))
+	for _, c := range composed {
+		if _, ok := imports[c.specURL]; !ok {
+			urls = append(urls, c.specURL)
+			imports[c.specURL] = []string{}
+		}
+		// directives namespaced with the spec name are available without importing
+		name, _, _ := parseSpecURL(c.specURL)
+		if !strings.HasPrefix(c.name, name+"__") {
+			imports[c.specURL] = append(imports[c.specURL], "@"+c.name)
+		}
+	}
+	sort.Strings(urls)
+
+	for _, url := range urls {
+		if len(imports[url]) == 0 {
+			fmt.Fprintf(out, "  @link(url: %s)\n", printValue(url))
+			continue
+		}
+		sort.Strings(imports[url])
+		fmt.Fprintf(out, "  @link(url: %s, import: %s)\n", printValue(url), printValue(imports[url]))
+	}
+
+	for _, c := range composed {
+		fmt.Fprintf(out, "  @%s(name: %s)\n", ComposeDirectiveDirective, printValue("@"+c.name))
+	}
+}
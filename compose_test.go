@@ -0,0 +1,96 @@
+package gofed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildComposedFederation(specURL string) *Federation {
+
+	cacheDirective := graphql.NewDirective(graphql.DirectiveConfig{
+		Name: "myorg__cacheTTL",
+		Args: graphql.FieldConfigArgument{
+			"seconds": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+		},
+		Locations: []string{graphql.DirectiveLocationFieldDefinition},
+	})
+
+	var productType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Product",
+			Fields: graphql.Fields{
+				"upc": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"price": &graphql.Field{
+					Type: graphql.Int,
+					Extensions: Directives(&DirectiveValue{
+						Name:   "myorg__cacheTTL",
+						Values: map[string]interface{}{"seconds": 30},
+					}),
+				},
+			},
+			Extensions: upcKey(),
+		},
+	)
+
+	fed := NewFederation()
+	fed.AddDirective(cacheDirective)
+	fed.ComposeDirective("@myorg__cacheTTL", specURL)
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"product": &graphql.Field{
+			Type: productType,
+		},
+	}, nil)
+
+	return fed
+}
+
+func TestComposeDirective(t *testing.T) {
+
+	fed := buildComposedFederation("https://myorg.dev/myorg/v1.0")
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+	expected := `extend schema
+  @link(url: "https://specs.apollo.dev/federation/v2.1", import: ["@composeDirective", "@key"])
+  @link(url: "https://myorg.dev/myorg/v1.0")
+  @composeDirective(name: "@myorg__cacheTTL")
+
+`
+	if !strings.HasPrefix(sdl, expected) {
+		t.Errorf("schema extension not printed:\n%s", sdl)
+	}
+	for _, line := range []string{
+		"directive @myorg__cacheTTL(seconds: Int!) on FIELD_DEFINITION",
+		"  price: Int @myorg__cacheTTL(seconds: 30)",
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+}
+
+func TestComposeDirectiveImport(t *testing.T) {
+
+	var out strings.Builder
+	printComposedDirectives([]*composedDirective{
+		{name: "cacheTTL", specURL: "https://myorg.dev/cache/v0.1"},
+	}, &out)
+
+	expected := "  @link(url: \"https://myorg.dev/cache/v0.1\", import: [\"@cacheTTL\"])\n  @composeDirective(name: \"@cacheTTL\")\n"
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	fed := buildComposedFederation("myorg")
+	if err := fed.Error(); err == nil || !strings.Contains(err.Error(), "invalid spec url") {
+		t.Errorf("expected spec url error, got: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package gofed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Context names a context set by an object, interface or union, its fields can
+// then be read by descendant fields with @fromContext
+func Context(name string) *DirectiveValue {
+	return &DirectiveValue{
+		Name: ContextDirective,
+		Values: map[string]interface{}{
+			"name": name,
+		},
+	}
+}
+
+// FromContext fills an argument from a context, field is a selection like
+// "$userContext { userId }"
+func FromContext(field string) *DirectiveValue {
+	return &DirectiveValue{
+		Name: FromContextDirective,
+		Values: map[string]interface{}{
+			"field": field,
+		},
+	}
+}
+
+// contextName returns the context referenced by a @fromContext field selection
+func contextName(field string) (string, error) {
+	field = strings.TrimSpace(field)
+	if !strings.HasPrefix(field, "$") {
+		return "", fmt.Errorf("field %q must start with a $context reference", field)
+	}
+	end := strings.IndexFunc(field[1:], func(r rune) bool {
+		return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	name := field[1:]
+	if end >= 0 {
+		name = field[1 : end+1]
+	}
+	if name == "" {
+		return "", fmt.Errorf("field %q must start with a $context reference", field)
+	}
+	if !strings.Contains(field, "{") {
+		return "", fmt.Errorf("field %q must select a field of the context", field)
+	}
+	return name, nil
+}
+
+// validateContexts checks every @fromContext references a context set with @context
+func validateContexts(sites []*directiveSite) error {
+	declared := make(map[string]bool)
+	for _, site := range sites {
+		for _, d := range findDirectives(site.directives, ContextDirective) {
+			if name, ok := d.Values["name"].(string); ok {
+				declared[name] = true
+			}
+		}
+	}
+
+	for _, site := range sites {
+		for _, d := range findDirectives(site.directives, FromContextDirective) {
+			field, ok := d.Values["field"].(string)
+			if !ok {
+				return fmt.Errorf("%s: @%s argument \"field\" must be a String", site.path, d.Name)
+			}
+			name, err := contextName(field)
+			if err != nil {
+				return fmt.Errorf("%s: @%s %s", site.path, d.Name, err)
+			}
+			if !declared[name] {
+				return fmt.Errorf("%s: @%s references unknown context %q", site.path, d.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// hasContextArguments reports if a field of the schema has @fromContext arguments
+func (f *Federation) hasContextArguments() bool {
+	for _, t := range f.schema.TypeMap() {
+		obj, ok := t.(*graphql.Object)
+		if !ok || strings.HasPrefix(obj.Name(), "__") {
+			continue
+		}
+		for _, field := range obj.Fields() {
+			for _, arg := range field.Args {
+				directives, _ := getDirectives(arg.Extensions)
+				if hasDirective(directives, FromContextDirective) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// applyContextArguments wraps the resolver of every field with @fromContext
+// arguments, so values sent in entity representations fill missing arguments
+func (f *Federation) applyContextArguments() error {
+	for _, t := range sortTypeMap(f.schema.TypeMap()) {
+		obj, ok := t.(*graphql.Object)
+		if !ok || strings.HasPrefix(obj.Name(), "__") {
+			continue
+		}
+		for _, field := range sortFields(obj.Fields()) {
+			args := make([]*graphql.Argument, 0)
+			for _, arg := range field.Args {
+				directives, err := getDirectives(arg.Extensions)
+				if err != nil {
+					return fmt.Errorf("%s.%s(%s:): %s", obj.Name(), field.Name, arg.Name(), err)
+				}
+				if hasDirective(directives, FromContextDirective) {
+					args = append(args, arg)
+				}
+			}
+			if len(args) > 0 {
+				field.Resolve = contextResolver(args, field.Resolve)
+			}
+		}
+	}
+	return nil
+}
+
+// contextResolver fills the given arguments, when the request doesn't set
+// them, from the representation of the entity being resolved. The router
+// sends context values in the representation under the argument name.
+func contextResolver(args []*graphql.Argument, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	if resolve == nil {
+		resolve = graphql.DefaultResolveFn
+	}
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		var rep map[string]interface{}
+		for _, arg := range args {
+			if _, ok := p.Args[arg.Name()]; ok {
+				continue
+			}
+			if rep == nil {
+				if rep = entityRepresentation(p.Info); rep == nil {
+					break
+				}
+			}
+			value, ok := rep[arg.Name()]
+			if !ok {
+				continue
+			}
+			if p.Args == nil {
+				p.Args = make(map[string]interface{})
+			}
+			p.Args[arg.Name()] = coerceContextValue(arg.Type, value)
+		}
+		return resolve(p)
+	}
+}
+
+// entityRepresentation returns the raw representation of the entity owning
+// the field being resolved, or nil when the field isn't directly under _entities
+func entityRepresentation(info graphql.ResolveInfo) map[string]interface{} {
+	path := info.Path
+	if path == nil || path.Prev == nil || path.Prev.Prev == nil || path.Prev.Prev.Prev != nil {
+		return nil
+	}
+	index, ok := path.Prev.Key.(int)
+	if !ok {
+		return nil
+	}
+	responseKey, ok := path.Prev.Prev.Key.(string)
+	if !ok {
+		return nil
+	}
+
+	operation, ok := info.Operation.(*ast.OperationDefinition)
+	if !ok || operation.SelectionSet == nil {
+		return nil
+	}
+	for _, selection := range operation.SelectionSet.Selections {
+		field, ok := selection.(*ast.Field)
+		if !ok || field.Name.Value != "_entities" {
+			continue
+		}
+		if field.Alias != nil && field.Alias.Value != responseKey || field.Alias == nil && responseKey != "_entities" {
+			continue
+		}
+		reps := representationsArgument(field, info.VariableValues)
+		if index >= len(reps) {
+			return nil
+		}
+		rep, _ := reps[index].(map[string]interface{})
+		return rep
+	}
+	return nil
+}
+
+// representationsArgument returns the representations passed to an _entities field
+func representationsArgument(field *ast.Field, variables map[string]interface{}) []interface{} {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "representations" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.Variable:
+			reps, _ := variables[value.Name.Value].([]interface{})
+			return reps
+		case *ast.ListValue:
+			reps := make([]interface{}, 0, len(value.Values))
+			for _, v := range value.Values {
+				reps = append(reps, AnyType.ParseLiteral(v))
+			}
+			return reps
+		}
+	}
+	return nil
+}
+
+// coerceContextValue converts a JSON context value to the argument's type
+func coerceContextValue(t graphql.Type, value interface{}) interface{} {
+	switch t := graphql.GetNamed(t).(type) {
+	case *graphql.Scalar:
+		return t.ParseValue(value)
+	case *graphql.Enum:
+		return t.ParseValue(value)
+	}
+	return value
+}
@@ -0,0 +1,135 @@
+package gofed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildContextFederation(fromContext string) *Federation {
+
+	var transactionType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Transaction",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+				"amount": &graphql.Field{
+					Type: graphql.String,
+					Args: graphql.FieldConfigArgument{
+						"currency": &graphql.ArgumentConfig{
+							Type:       graphql.String,
+							Extensions: Directives(FromContext(fromContext)),
+						},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						currency, ok := p.Args["currency"].(string)
+						if !ok {
+							return nil, nil
+						}
+						return "10 " + currency, nil
+					},
+				},
+			},
+			Extensions: mediaKey(),
+		},
+	)
+
+	var userType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "User",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+				"transactions": &graphql.Field{
+					Type: graphql.NewList(transactionType),
+				},
+			},
+			Extensions: Directives(Context("userContext")),
+		},
+	)
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"me": &graphql.Field{
+			Type: userType,
+		},
+	}, nil)
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		return map[string]interface{}{"id": rep.KeyValue}, nil
+	})
+
+	return fed
+}
+
+func TestContextSDL(t *testing.T) {
+
+	fed := buildContextFederation("$userContext { currency }")
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`  @link(url: "https://specs.apollo.dev/federation/v2.8", import: ["@context", "@fromContext", "@key"])`,
+		`type User @context(name: "userContext") {`,
+		`  amount(currency: String @fromContext(field: "$userContext { currency }")): String`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+
+	fed = buildContextFederation("$accountContext { currency }")
+	if err := fed.Error(); err == nil || !strings.Contains(err.Error(), `unknown context "accountContext"`) {
+		t.Errorf("expected unknown context error, got: %v", err)
+	}
+}
+
+func TestContextArguments(t *testing.T) {
+
+	fed := buildContextFederation("$userContext { currency }")
+
+	tests := []struct {
+		query     string
+		variables map[string]interface{}
+		expected  string
+	}{
+		{
+			`query ($r: [_Any!]!) { _entities(representations: $r) { ... on Transaction { id amount } } }`,
+			map[string]interface{}{"r": []interface{}{
+				map[string]interface{}{"__typename": "Transaction", "id": "1", "currency": "EUR"},
+			}},
+			`{"_entities":[{"amount":"10 EUR","id":"1"}]}`,
+		},
+		{
+			`{ _entities(representations: [{__typename: "Transaction", id: "1", currency: "EUR"}]) { ... on Transaction { amount(currency: "USD") } } }`,
+			nil,
+			`{"_entities":[{"amount":"10 USD"}]}`,
+		},
+		{
+			`{ e: _entities(representations: [{__typename: "Transaction", id: "1"}, {__typename: "Transaction", id: "2", currency: "GBP"}]) { ... on Transaction { amount } } }`,
+			nil,
+			`{"e":[{"amount":null},{"amount":"10 GBP"}]}`,
+		},
+	}
+	for i, test := range tests {
+		r := graphql.Do(graphql.Params{
+			Schema:         *fed.Schema(),
+			RequestString:  test.query,
+			VariableValues: test.variables,
+		})
+		if len(r.Errors) > 0 {
+			t.Errorf("test %d: unexpected errors: %v", i, r.Errors)
+			continue
+		}
+		rJSON, _ := json.Marshal(r.Data)
+		if string(rJSON) != test.expected {
+			t.Errorf("test %d: unexpected result %s", i, rJSON)
+		}
+	}
+}
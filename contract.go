@@ -0,0 +1,506 @@
+package gofed
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// ContractConfig selects the schema elements of a contract variant by their @tag
+type ContractConfig struct {
+	// IncludeTags keeps only the object and interface types, and their fields,
+	// tagged with one of these tags. Every element is kept when empty.
+	IncludeTags []string
+	// ExcludeTags removes every element tagged with one of these tags, it
+	// takes precedence over IncludeTags
+	ExcludeTags []string
+}
+
+// Contract builds a contract variant of the subgraph, with the types, fields,
+// arguments and enum values filtered by their @tag. The returned Federation
+// shares the resolvers and settings of f and serves the filtered schema and
+// _service SDL. It fails if a kept element references a removed one.
+func (f *Federation) Contract(config ContractConfig) (*Federation, error) {
+	if f.err != nil {
+		return nil, fmt.Errorf("contract: %s", f.err)
+	}
+	if f.schema == nil {
+		return nil, fmt.Errorf("contract: BuildSubgraphSchema must be called first")
+	}
+
+	b := newContractBuilder(f.schema, config)
+	queryFields, mutationFields, subscriptionFields, err := b.build(f.entityType)
+	if err != nil {
+		return nil, fmt.Errorf("contract: %s", err)
+	}
+
+	contract := &Federation{
+		entityResolver:      f.entityResolver,
+		batchEntityResolver: f.batchEntityResolver,
+		extensionStyle:      f.extensionStyle,
+		directives:          f.directives,
+		repeatable:          f.repeatable,
+		composed:            f.composed,
+		maxCost:             f.maxCost,
+		defaultListSize:     f.defaultListSize,
+		overrideRollout:     f.overrideRollout,
+		hideInaccessible:    f.hideInaccessible,
+		maskErrors:          f.maskErrors,
+		fromSDL:             f.fromSDL,
+		linkVersion:         f.linkVersion,
+		maxRequestSize:      f.maxRequestSize,
+		// the copied resolvers already carry the context and authorization wrappers
+		wrappedResolvers: true,
+	}
+	contract.typedResolvers = make(map[string]typedEntityResolver, len(f.typedResolvers))
+	for name, resolver := range f.typedResolvers {
+		if !b.removed[name] {
+			contract.typedResolvers[name] = resolver
+		}
+	}
+	// types added with AddTypes, from SDL or by WrapSchema stay in the contract
+	contract.types = b.copiedTypes(f.types)
+	contract.buildSchema(queryFields, mutationFields, subscriptionObject(subscriptionFields))
+	if contract.err != nil {
+		return nil, fmt.Errorf("contract: %s", contract.err)
+	}
+	return contract, nil
+}
+
+// contractBuilder copies the types of a schema, leaving out the elements
+// removed by a contract
+type contractBuilder struct {
+	config  ContractConfig
+	schema  *graphql.Schema
+	types   map[string]graphql.Type
+	fields  map[string]graphql.Fields
+	removed map[string]bool
+}
+
+func newContractBuilder(schema *graphql.Schema, config ContractConfig) *contractBuilder {
+	return &contractBuilder{
+		config:  config,
+		schema:  schema,
+		types:   make(map[string]graphql.Type),
+		fields:  make(map[string]graphql.Fields),
+		removed: make(map[string]bool),
+	}
+}
+
+// copiedTypes returns the copies of the given types kept in the contract
+func (b *contractBuilder) copiedTypes(types []graphql.Type) []graphql.Type {
+	copied := make([]graphql.Type, 0, len(types))
+	for _, t := range types {
+		if c, ok := b.types[t.Name()]; ok {
+			copied = append(copied, c)
+		}
+	}
+	return copied
+}
+
+// subscriptionObject returns the Subscription root type of the given fields,
+// nil when there are none
+func subscriptionObject(fields graphql.Fields) *graphql.Object {
+	if len(fields) == 0 {
+		return nil
+	}
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Subscription",
+		Fields: fields,
+	})
+}
+
+// tagged reports if the extensions hold a @tag with one of the given names
+func tagged(extensions map[string]interface{}, tags []string) bool {
+	directives, _ := getDirectives(extensions)
+	for _, d := range findDirectives(directives, TagDirective) {
+		if name, ok := d.Values["name"].(string); ok && containsString(tags, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *contractBuilder) excluded(extensions map[string]interface{}) bool {
+	return tagged(extensions, b.config.ExcludeTags)
+}
+
+// keepField reports if a field stays in the contract, parentIncluded is set
+// when its parent type carries an included tag
+func (b *contractBuilder) keepField(field *graphql.FieldDefinition, parentIncluded bool) bool {
+	if b.excluded(field.Extensions) {
+		return false
+	}
+	return len(b.config.IncludeTags) == 0 || parentIncluded || tagged(field.Extensions, b.config.IncludeTags)
+}
+
+// keptFields returns the fields of an object or interface staying in the contract
+func (b *contractBuilder) keptFields(extensions map[string]interface{}, fields graphql.FieldDefinitionMap, root bool) []*graphql.FieldDefinition {
+	if b.excluded(extensions) {
+		return nil
+	}
+	parentIncluded := !root && tagged(extensions, b.config.IncludeTags)
+	kept := make([]*graphql.FieldDefinition, 0, len(fields))
+	for _, field := range sortFields(fields) {
+		if b.keepField(field, parentIncluded) {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+func (b *contractBuilder) isContractType(entityType *graphql.Union, name string) bool {
+	if s := b.schema.SubscriptionType(); s != nil && name == s.Name() {
+		return false
+	}
+	return !isSkippedType(b.schema, entityType, name) && name != serviceType.Name()
+}
+
+// build copies the kept types and returns the kept query, mutation and
+// subscription fields
+func (b *contractBuilder) build(entityType *graphql.Union) (graphql.Fields, graphql.Fields, graphql.Fields, error) {
+	types := make([]graphql.Type, 0)
+	for _, t := range sortTypeMap(b.schema.TypeMap()) {
+		if b.isContractType(entityType, t.Name()) {
+			types = append(types, t)
+		}
+	}
+
+	// decide which types are removed, unions last as they depend on their members
+	kept := make(map[string][]*graphql.FieldDefinition)
+	for _, t := range types {
+		switch t := t.(type) {
+		case *graphql.Object:
+			kept[t.Name()] = b.keptFields(t.Extensions(), t.Fields(), false)
+			b.removed[t.Name()] = len(kept[t.Name()]) == 0
+		case *graphql.Interface:
+			kept[t.Name()] = b.keptFields(t.Extensions(), t.Fields(), false)
+			b.removed[t.Name()] = len(kept[t.Name()]) == 0
+		case *graphql.Enum:
+			b.removed[t.Name()] = b.excluded(t.Extensions()) || len(b.enumValues(t)) == 0
+		case *graphql.InputObject:
+			b.removed[t.Name()] = b.excluded(t.Extensions()) || len(b.inputFields(t)) == 0
+		case *graphql.Scalar:
+			b.removed[t.Name()] = b.excluded(t.Extensions())
+		}
+	}
+	for _, t := range types {
+		if t, ok := t.(*graphql.Union); ok {
+			b.removed[t.Name()] = b.excluded(t.Extensions()) || len(b.unionMembers(t)) == 0
+		}
+	}
+
+	// copy the remaining types, fields are filled in below so types can
+	// reference each other, unions need their members copied first
+	for _, t := range types {
+		if _, ok := t.(*graphql.Union); !ok && !b.removed[t.Name()] {
+			b.types[t.Name()] = b.copyType(t)
+		}
+	}
+	for _, t := range types {
+		if _, ok := t.(*graphql.Union); ok && !b.removed[t.Name()] {
+			b.types[t.Name()] = b.copyType(t)
+		}
+	}
+	for _, t := range types {
+		if t, ok := t.(*graphql.InputObject); ok && !b.removed[t.Name()] {
+			for _, field := range b.inputFields(t) {
+				if _, err := b.copyInputType(field.Type); err != nil {
+					return nil, nil, nil, fmt.Errorf("%s.%s: %s", t.Name(), field.Name(), err)
+				}
+			}
+		}
+	}
+	for _, t := range types {
+		fields, ok := kept[t.Name()]
+		if !ok || b.removed[t.Name()] {
+			continue
+		}
+		copied, err := b.copyFields(t.Name(), fields)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		b.fields[t.Name()] = copied
+		if obj, ok := t.(*graphql.Object); ok {
+			if err := b.checkKeys(obj, copied); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+
+	queryFields := graphql.Fields{}
+	if q := b.schema.QueryType(); q != nil {
+		fields := make([]*graphql.FieldDefinition, 0)
+		for _, field := range b.keptFields(nil, q.Fields(), true) {
+			if field.Name != "_entities" && field.Name != "_service" {
+				fields = append(fields, field)
+			}
+		}
+		var err error
+		if queryFields, err = b.copyFields(q.Name(), fields); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	var mutationFields graphql.Fields
+	if m := b.schema.MutationType(); m != nil {
+		var err error
+		if mutationFields, err = b.copyFields(m.Name(), b.keptFields(nil, m.Fields(), true)); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var subscriptionFields graphql.Fields
+	if s := b.schema.SubscriptionType(); s != nil {
+		var err error
+		if subscriptionFields, err = b.copyFields(s.Name(), b.keptFields(nil, s.Fields(), true)); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return queryFields, mutationFields, subscriptionFields, nil
+}
+
+func (b *contractBuilder) enumValues(t *graphql.Enum) graphql.EnumValueConfigMap {
+	values := make(graphql.EnumValueConfigMap)
+	for _, v := range t.Values() {
+		if b.excluded(v.Extensions) {
+			continue
+		}
+		values[v.Name] = &graphql.EnumValueConfig{
+			Value:             v.Value,
+			DeprecationReason: v.DeprecationReason,
+			Description:       v.Description,
+			Extensions:        v.Extensions,
+		}
+	}
+	return values
+}
+
+func (b *contractBuilder) inputFields(t *graphql.InputObject) []*graphql.InputObjectField {
+	fields := make([]*graphql.InputObjectField, 0)
+	for _, field := range sortInputFields(t.Fields()) {
+		if !b.excluded(field.Extensions) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func (b *contractBuilder) unionMembers(t *graphql.Union) []*graphql.Object {
+	members := make([]*graphql.Object, 0)
+	for _, member := range t.Types() {
+		if !b.removed[member.Name()] {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// copyType creates the contract copy of a named type
+func (b *contractBuilder) copyType(t graphql.Type) graphql.Type {
+	switch t := t.(type) {
+	case *graphql.Object:
+		name := t.Name()
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name:        name,
+			Description: t.Description(),
+			Extensions:  t.Extensions(),
+			IsTypeOf:    t.IsTypeOf,
+			Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+				return b.fields[name]
+			}),
+			Interfaces: (graphql.InterfacesThunk)(func() []*graphql.Interface {
+				interfaces := make([]*graphql.Interface, 0)
+				for _, iface := range t.Interfaces() {
+					if copied, ok := b.types[iface.Name()].(*graphql.Interface); ok {
+						interfaces = append(interfaces, copied)
+					}
+				}
+				return interfaces
+			}),
+		})
+	case *graphql.Interface:
+		name := t.Name()
+		return graphql.NewInterface(graphql.InterfaceConfig{
+			Name:        name,
+			Description: t.Description(),
+			Extensions:  t.Extensions(),
+			ResolveType: b.resolveType(t.ResolveType),
+			Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+				return b.fields[name]
+			}),
+		})
+	case *graphql.Union:
+		members := make([]*graphql.Object, 0)
+		for _, member := range b.unionMembers(t) {
+			members = append(members, b.types[member.Name()].(*graphql.Object))
+		}
+		return graphql.NewUnion(graphql.UnionConfig{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Extensions:  t.Extensions(),
+			ResolveType: b.resolveType(t.ResolveType),
+			Types:       members,
+		})
+	case *graphql.Enum:
+		return graphql.NewEnum(graphql.EnumConfig{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Extensions:  t.Extensions(),
+			Values:      b.enumValues(t),
+		})
+	case *graphql.InputObject:
+		name := t.Name()
+		return graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:        name,
+			Description: t.Description(),
+			Extensions:  t.Extensions(),
+			Fields: (graphql.InputObjectConfigFieldMapThunk)(func() graphql.InputObjectConfigFieldMap {
+				fields := make(graphql.InputObjectConfigFieldMap)
+				for _, field := range b.inputFields(t) {
+					fieldType, _ := b.copyInputType(field.Type)
+					fields[field.Name()] = &graphql.InputObjectFieldConfig{
+						Type:         fieldType,
+						DefaultValue: field.DefaultValue,
+						Description:  field.Description(),
+						Extensions:   field.Extensions,
+					}
+				}
+				return fields
+			}),
+		})
+	default:
+		// scalars are shared with the original schema
+		return t
+	}
+}
+
+// resolveType maps the objects returned by an original ResolveType to their copies
+func (b *contractBuilder) resolveType(resolveType graphql.ResolveTypeFn) graphql.ResolveTypeFn {
+	if resolveType == nil {
+		return nil
+	}
+	return func(p graphql.ResolveTypeParams) *graphql.Object {
+		obj := resolveType(p)
+		if obj == nil {
+			return nil
+		}
+		copied, _ := b.types[obj.Name()].(*graphql.Object)
+		return copied
+	}
+}
+
+// copyFields copies the given fields, with their removed arguments left out
+func (b *contractBuilder) copyFields(parent string, fields []*graphql.FieldDefinition) (graphql.Fields, error) {
+	copied := make(graphql.Fields, len(fields))
+	for _, field := range fields {
+		path := parent + "." + field.Name
+		fieldType, err := b.copyOutputType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		args := make(graphql.FieldConfigArgument)
+		for _, arg := range field.Args {
+			if b.excluded(arg.Extensions) {
+				if _, nonNull := arg.Type.(*graphql.NonNull); nonNull && arg.DefaultValue == nil {
+					return nil, fmt.Errorf("%s(%s:): required argument cannot be removed", path, arg.Name())
+				}
+				continue
+			}
+			argType, err := b.copyInputType(arg.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s(%s:): %s", path, arg.Name(), err)
+			}
+			args[arg.Name()] = &graphql.ArgumentConfig{
+				Type:         argType,
+				DefaultValue: arg.DefaultValue,
+				Description:  arg.Description(),
+				Extensions:   arg.Extensions,
+			}
+		}
+		copied[field.Name] = &graphql.Field{
+			Name:              field.Name,
+			Type:              fieldType,
+			Args:              args,
+			Resolve:           field.Resolve,
+			Subscribe:         field.Subscribe,
+			DeprecationReason: field.DeprecationReason,
+			Description:       field.Description,
+			Extensions:        field.Extensions,
+		}
+	}
+	return copied, nil
+}
+
+// copyOutputType returns the contract copy of t, keeping its list and non-null wrappers
+func (b *contractBuilder) copyOutputType(t graphql.Output) (graphql.Output, error) {
+	switch t := t.(type) {
+	case *graphql.List:
+		ofType, err := b.copyOutputType(t.OfType)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewList(ofType), nil
+	case *graphql.NonNull:
+		ofType, err := b.copyOutputType(t.OfType)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewNonNull(ofType), nil
+	}
+	copied, err := b.namedType(t)
+	if err != nil {
+		return nil, err
+	}
+	return copied.(graphql.Output), nil
+}
+
+// copyInputType returns the contract copy of t, keeping its list and non-null wrappers
+func (b *contractBuilder) copyInputType(t graphql.Input) (graphql.Input, error) {
+	switch t := t.(type) {
+	case *graphql.List:
+		ofType, err := b.copyInputType(t.OfType)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewList(ofType), nil
+	case *graphql.NonNull:
+		ofType, err := b.copyInputType(t.OfType)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewNonNull(ofType), nil
+	}
+	copied, err := b.namedType(t)
+	if err != nil {
+		return nil, err
+	}
+	return copied.(graphql.Input), nil
+}
+
+func (b *contractBuilder) namedType(t graphql.Type) (graphql.Type, error) {
+	if b.removed[t.Name()] {
+		return nil, fmt.Errorf("references removed type %s", t.Name())
+	}
+	if copied, ok := b.types[t.Name()]; ok {
+		return copied, nil
+	}
+	// built-in scalars
+	return t, nil
+}
+
+// checkKeys checks the key fields of an entity are kept
+func (b *contractBuilder) checkKeys(obj *graphql.Object, fields graphql.Fields) error {
+	keys, err := keyDirectives(obj.Extensions())
+	if err != nil {
+		return fmt.Errorf("%s: %s", obj.Name(), err)
+	}
+	for _, key := range keys {
+		for _, name := range topLevelKeyFields(key) {
+			if _, ok := fields[name]; !ok {
+				return fmt.Errorf("%s: key field %s is removed", obj.Name(), name)
+			}
+		}
+	}
+	return nil
+}
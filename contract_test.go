@@ -0,0 +1,224 @@
+package gofed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildContractFederation() *Federation {
+
+	var colorEnum = graphql.NewEnum(graphql.EnumConfig{
+		Name: "Color",
+		Values: graphql.EnumValueConfigMap{
+			"RED": &graphql.EnumValueConfig{
+				Value: "red",
+			},
+			"PROTOTYPE": &graphql.EnumValueConfig{
+				Value:      "prototype",
+				Extensions: Directives(Tag("internal")),
+			},
+		},
+	})
+
+	var reviewType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Review",
+			Fields: graphql.Fields{
+				"body": &graphql.Field{
+					Type: graphql.String,
+				},
+			},
+		},
+	)
+
+	var productType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Product",
+			Fields: graphql.Fields{
+				"upc": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"name": &graphql.Field{
+					Type: graphql.String,
+					Args: graphql.FieldConfigArgument{
+						"locale": &graphql.ArgumentConfig{
+							Type:       graphql.String,
+							Extensions: Directives(Tag("internal")),
+						},
+					},
+				},
+				"cost": &graphql.Field{
+					Type:       graphql.Int,
+					Extensions: Directives(Tag("internal")),
+				},
+				"color": &graphql.Field{
+					Type: colorEnum,
+				},
+				"reviews": &graphql.Field{
+					Type: graphql.NewList(reviewType),
+				},
+			},
+			Extensions: Directives(
+				&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "upc"}},
+				Tag("public"),
+			),
+		},
+	)
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"product": &graphql.Field{
+			Type:       productType,
+			Extensions: Directives(Tag("public")),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"upc": "1", "name": "Ring", "cost": 5, "color": "red"}, nil
+			},
+		},
+		"prototypes": &graphql.Field{
+			Type:       graphql.NewList(productType),
+			Extensions: Directives(Tag("internal")),
+		},
+	}, nil)
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		return map[string]interface{}{"upc": rep.KeyValue, "name": "Ring"}, nil
+	})
+
+	return fed
+}
+
+func TestContractExclude(t *testing.T) {
+
+	fed := buildContractFederation()
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	contract, err := fed.Contract(ContractConfig{ExcludeTags: []string{"internal"}})
+	if err != nil {
+		t.Fatalf("unexpected contract error: %s", err)
+	}
+
+	sdl := contract.PrintSDL()
+	for _, removed := range []string{"cost", "prototypes", "PROTOTYPE", "locale"} {
+		if strings.Contains(sdl, removed) {
+			t.Errorf("sdl contains removed element %s:\n%s", removed, sdl)
+		}
+	}
+	for _, kept := range []string{"  name: String", "  reviews: [Review]"} {
+		if !strings.Contains(sdl, kept+"\n") {
+			t.Errorf("sdl is missing %q:\n%s", kept, sdl)
+		}
+	}
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{`{ product { upc name color } }`, `{"data":{"product":{"color":"RED","name":"Ring","upc":"1"}}}`},
+		{`{ _entities(representations: [{__typename: "Product", upc: "2"}]) { ... on Product { upc name } } }`, `{"data":{"_entities":[{"name":"Ring","upc":"2"}]}}`},
+		{`{ product { cost } }`, `Cannot query field \"cost\" on type \"Product\".`},
+	}
+	for _, test := range tests {
+		r := graphql.Do(graphql.Params{
+			Schema:        *contract.Schema(),
+			RequestString: test.query,
+		})
+		rJSON, _ := json.Marshal(r)
+		if !strings.Contains(string(rJSON), test.expected) {
+			t.Errorf("query %s: unexpected result %s", test.query, rJSON)
+		}
+	}
+	if result := graphql.Do(graphql.Params{Schema: *contract.Schema(), RequestString: `{ _service { sdl } }`}); len(result.Errors) > 0 {
+		t.Errorf("unexpected _service errors: %v", result.Errors)
+	} else if result.Data.(map[string]interface{})["_service"].(map[string]interface{})["sdl"] != sdl {
+		t.Errorf("_service sdl doesn't match the contract sdl")
+	}
+
+	// the original schema is left untouched
+	if !strings.Contains(fed.PrintSDL(), "cost: Int") {
+		t.Errorf("contract changed the original schema")
+	}
+}
+
+func TestContractDanglingReferences(t *testing.T) {
+
+	fed := buildContractFederation()
+
+	tests := []struct {
+		config   ContractConfig
+		expected string
+	}{
+		{ContractConfig{IncludeTags: []string{"public"}}, "Product.reviews: references removed type Review"},
+		{ContractConfig{ExcludeTags: []string{"public"}}, "Query.prototypes: references removed type Product"},
+	}
+	for _, test := range tests {
+		_, err := fed.Contract(test.config)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%v: expected error %q, got: %v", test.config, test.expected, err)
+		}
+	}
+}
+
+func TestContractKeepsTypesAndSubscriptions(t *testing.T) {
+
+	reviewType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Review",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		},
+		Extensions: mediaKey(),
+	})
+	existing, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"version": &graphql.Field{
+					Type: graphql.String,
+				},
+			},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Subscription",
+			Fields: graphql.Fields{
+				"versionChanged": &graphql.Field{
+					Type: graphql.String,
+				},
+				"debug": &graphql.Field{
+					Type:       graphql.String,
+					Extensions: Directives(Tag("internal")),
+				},
+			},
+		}),
+		// Review is only returned by _entities
+		Types: []graphql.Type{reviewType},
+	})
+	if err != nil {
+		t.Fatalf("unexpected schema error: %s", err)
+	}
+
+	fed := NewFederation()
+	fed.WrapSchema(&existing, nil)
+	contract, err := fed.Contract(ContractConfig{ExcludeTags: []string{"internal"}})
+	if err != nil {
+		t.Fatalf("unexpected contract error: %s", err)
+	}
+
+	if contract.Schema().Type("Review") == nil || len(contract.entityType.Types()) != 1 {
+		t.Errorf("Review was left out of the contract")
+	}
+	subscription := contract.Schema().SubscriptionType()
+	if subscription == nil {
+		t.Fatal("subscription root was left out of the contract")
+	}
+	if _, ok := subscription.Fields()["versionChanged"]; !ok {
+		t.Error("subscription field versionChanged was left out of the contract")
+	}
+	if _, ok := subscription.Fields()["debug"]; ok {
+		t.Error("excluded subscription field debug is in the contract")
+	}
+}
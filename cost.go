@@ -0,0 +1,536 @@
+package gofed
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// demand control directive names
+const (
+	CostDirective     = "cost"
+	ListSizeDirective = "listSize"
+)
+
+// DefaultListSize is the size assumed for lists without a @listSize
+const DefaultListSize = 10
+
+// mutations carry a base cost on top of their selections
+const mutationCost = 10
+
+// ErrCostExceeded is returned by CheckCost when an operation costs more than the configured maximum
+var ErrCostExceeded = errors.New("operation cost exceeds the maximum")
+
+// Cost sets the weight of a field, argument, input field or type in cost analysis
+func Cost(weight int) *DirectiveValue {
+	return &DirectiveValue{
+		Name: CostDirective,
+		Values: map[string]interface{}{
+			"weight": weight,
+		},
+	}
+}
+
+// ListSizeConfig configures how the size of a list field is estimated
+type ListSizeConfig struct {
+	// AssumedSize is the size used when no slicing argument is given
+	AssumedSize int
+	// SlicingArguments are arguments, like "first", that limit the list size
+	SlicingArguments []string
+	// SizedFields are the child fields the size applies to, for connection types
+	SizedFields []string
+}
+
+// ListSize sets how the size of a list field is estimated in cost analysis
+func ListSize(config ListSizeConfig) *DirectiveValue {
+	values := make(map[string]interface{})
+	if config.AssumedSize > 0 {
+		values["assumedSize"] = config.AssumedSize
+	}
+	if len(config.SlicingArguments) > 0 {
+		values["slicingArguments"] = config.SlicingArguments
+	}
+	if len(config.SizedFields) > 0 {
+		values["sizedFields"] = config.SizedFields
+	}
+	return &DirectiveValue{
+		Name:   ListSizeDirective,
+		Values: values,
+	}
+}
+
+// SetMaxCost sets the maximum cost CheckCost accepts, 0 disables the check
+func (f *Federation) SetMaxCost(max int) {
+	f.maxCost = max
+}
+
+// SetDefaultListSize sets the size assumed for lists without a @listSize
+func (f *Federation) SetDefaultListSize(size int) {
+	f.defaultListSize = size
+}
+
+// CheckCost estimates the cost of an operation and returns an error wrapping
+// ErrCostExceeded when it is over the maximum set with SetMaxCost
+func (f *Federation) CheckCost(requestString, operationName string, variables map[string]interface{}) error {
+	if f.maxCost <= 0 {
+		return nil
+	}
+	cost, err := f.EstimateCost(requestString, operationName, variables)
+	if err != nil {
+		return err
+	}
+	return f.checkCost(cost)
+}
+
+func (f *Federation) checkCost(cost int) error {
+	if cost > f.maxCost {
+		return fmt.Errorf("%w: cost %d, maximum %d", ErrCostExceeded, cost, f.maxCost)
+	}
+	return nil
+}
+
+// EstimateCost statically scores an operation against the subgraph schema
+// using the @cost and @listSize directives, operations that don't validate
+// against the schema return an error
+func (f *Federation) EstimateCost(requestString, operationName string, variables map[string]interface{}) (int, error) {
+	if f.schema == nil {
+		return 0, fmt.Errorf("subgraph schema has not been built")
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: requestString})
+	if err != nil {
+		return 0, err
+	}
+	// only valid operations are scored
+	if errs := validateDocument(f.schema, doc); len(errs) > 0 {
+		return 0, fmt.Errorf("invalid operation: %s", errs[0].Message)
+	}
+	return f.documentCost(doc, operationName, variables)
+}
+
+// documentCost scores an operation of a document already validated against
+// the subgraph schema
+func (f *Federation) documentCost(doc *ast.Document, operationName string, variables map[string]interface{}) (int, error) {
+	c := &costCalculator{
+		schema:          f.schema,
+		variables:       variables,
+		fragments:       make(map[string]*ast.FragmentDefinition),
+		spreading:       make(map[string]bool),
+		fragmentCosts:   make(map[fragmentCostKey]int),
+		defaultListSize: f.defaultListSize,
+	}
+	if c.defaultListSize <= 0 {
+		c.defaultListSize = DefaultListSize
+	}
+
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				if operation != nil && operationName == "" {
+					return 0, fmt.Errorf("must provide operation name if query contains multiple operations")
+				}
+				operation = def
+			}
+		}
+	}
+	if operation == nil {
+		return 0, fmt.Errorf("unknown operation %q", operationName)
+	}
+
+	var root *graphql.Object
+	cost := 0
+	switch operation.Operation {
+	case ast.OperationTypeMutation:
+		root = f.schema.MutationType()
+		cost += mutationCost
+	case ast.OperationTypeSubscription:
+		root = f.schema.SubscriptionType()
+	default:
+		root = f.schema.QueryType()
+	}
+	if root == nil {
+		return 0, fmt.Errorf("schema does not support %s operations", operation.Operation)
+	}
+
+	selectionCost, err := c.selectionSetCost(root, operation.SelectionSet, nil)
+	if err != nil {
+		return 0, err
+	}
+	return addCost(cost, selectionCost), nil
+}
+
+// validateDocument validates a document against the schema. Fragment cycles
+// are rejected on their own first, graphql-go's other rules recurse through
+// them until the stack overflows.
+func validateDocument(schema *graphql.Schema, doc *ast.Document) []gqlerrors.FormattedError {
+	for _, rules := range [][]graphql.ValidationRuleFn{{graphql.NoFragmentCyclesRule}, graphql.SpecifiedRules} {
+		if result := graphql.ValidateDocument(schema, doc, rules); !result.IsValid {
+			return result.Errors
+		}
+	}
+	return nil
+}
+
+// costCalculator holds the state of a single cost estimation
+type costCalculator struct {
+	schema    *graphql.Schema
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+	// spreading holds the fragments spread on the path being scored
+	spreading map[string]bool
+	// fragmentCosts holds the scored fragments, a fragment spread many times
+	// is only walked once
+	fragmentCosts   map[fragmentCostKey]int
+	defaultListSize int
+}
+
+type fragmentCostKey struct {
+	fragment string
+	parent   string
+}
+
+// selectionSetCost scores a selection set on a composite type. Fields selected
+// through fragments on other type conditions are scored per type and the most
+// expensive type is used. sizedFields holds the list size for child fields
+// named in the parent's @listSize(sizedFields:).
+func (c *costCalculator) selectionSetCost(parent graphql.Type, set *ast.SelectionSet, sizedFields map[string]int) (int, error) {
+	if set == nil {
+		return 0, nil
+	}
+
+	common := 0
+	conditional := make(map[string]int)
+
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			cost, err := c.fieldCost(parent, selection, sizedFields)
+			if err != nil {
+				return 0, err
+			}
+			common = addCost(common, cost)
+		case *ast.InlineFragment:
+			target := parent
+			if selection.TypeCondition != nil {
+				target = c.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			if target == nil {
+				return 0, fmt.Errorf("unknown type %q", selection.TypeCondition.Name.Value)
+			}
+			cost, err := c.selectionSetCost(target, selection.SelectionSet, sizedFields)
+			if err != nil {
+				return 0, err
+			}
+			if target == parent {
+				common = addCost(common, cost)
+			} else {
+				conditional[target.Name()] = addCost(conditional[target.Name()], cost)
+			}
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := c.fragments[name]
+			if !ok {
+				return 0, fmt.Errorf("unknown fragment %q", name)
+			}
+			if c.spreading[name] {
+				return 0, fmt.Errorf("fragment %q spreads itself", name)
+			}
+			target := c.schema.Type(fragment.TypeCondition.Name.Value)
+			if target == nil {
+				return 0, fmt.Errorf("unknown type %q", fragment.TypeCondition.Name.Value)
+			}
+			// sized fields depend on the field spreading the fragment, those spreads aren't shared
+			key := fragmentCostKey{fragment: name, parent: parent.Name()}
+			cost, scored := c.fragmentCosts[key]
+			if !scored || len(sizedFields) > 0 {
+				c.spreading[name] = true
+				var err error
+				cost, err = c.selectionSetCost(target, fragment.SelectionSet, sizedFields)
+				delete(c.spreading, name)
+				if err != nil {
+					return 0, err
+				}
+				if len(sizedFields) == 0 {
+					c.fragmentCosts[key] = cost
+				}
+			}
+			if target == parent {
+				common = addCost(common, cost)
+			} else {
+				conditional[target.Name()] = addCost(conditional[target.Name()], cost)
+			}
+		}
+	}
+
+	max := 0
+	for _, cost := range conditional {
+		if cost > max {
+			max = cost
+		}
+	}
+	return addCost(common, max), nil
+}
+
+// fieldCost scores a single field selection including its arguments and sub selections
+func (c *costCalculator) fieldCost(parent graphql.Type, field *ast.Field, sizedFields map[string]int) (int, error) {
+	name := field.Name.Value
+	if name == "__typename" || name == "__schema" || name == "__type" {
+		return 0, nil
+	}
+
+	var fields graphql.FieldDefinitionMap
+	switch parent := parent.(type) {
+	case *graphql.Object:
+		fields = parent.Fields()
+	case *graphql.Interface:
+		fields = parent.Fields()
+	}
+	def, ok := fields[name]
+	if !ok {
+		return 0, fmt.Errorf("cannot query field %q on type %q", name, parent.Name())
+	}
+
+	directives, err := getDirectives(def.Extensions)
+	if err != nil {
+		return 0, err
+	}
+	named, _ := graphql.GetNamed(def.Type).(graphql.Type)
+
+	// the field's own weight, its type's weight, or the default for the kind of type
+	weight := 0
+	if isCompositeType(named) {
+		weight = 1
+	}
+	if w, ok := directiveWeight(directives); ok {
+		weight = w
+	} else if typeDirectives, err := getDirectives(namedTypeExtensions(named)); err == nil {
+		if w, ok := directiveWeight(typeDirectives); ok {
+			weight = w
+		}
+	}
+
+	args := make(map[string]interface{}, len(field.Arguments))
+	for _, arg := range field.Arguments {
+		args[arg.Name.Value] = c.argumentValue(arg.Value)
+	}
+
+	argCost, err := c.argumentsCost(def.Args, args)
+	if err != nil {
+		return 0, err
+	}
+
+	var listSize *DirectiveValue
+	if found := findDirectives(directives, ListSizeDirective); len(found) > 0 {
+		listSize = found[0]
+	}
+
+	size := 1
+	var childSizes map[string]int
+	if listSize != nil && len(stringList(listSize.Values["sizedFields"])) > 0 {
+		// the size applies to the listed child fields instead of this field
+		childSizes = make(map[string]int)
+		for _, child := range stringList(listSize.Values["sizedFields"]) {
+			childSizes[child] = c.listSize(listSize, args)
+		}
+	} else if isListType(def.Type) {
+		size = c.listSize(listSize, args)
+	}
+	if s, ok := sizedFields[name]; ok {
+		size = s
+	}
+
+	childCost, err := c.selectionSetCost(named, field.SelectionSet, childSizes)
+	if err != nil {
+		return 0, err
+	}
+
+	return addCost(argCost, mulCost(size, addCost(weight, childCost))), nil
+}
+
+// argumentsCost adds the @cost weights of the provided arguments and input fields
+func (c *costCalculator) argumentsCost(defs []*graphql.Argument, args map[string]interface{}) (int, error) {
+	cost := 0
+	for _, def := range defs {
+		value, ok := args[def.Name()]
+		if !ok || value == nil {
+			continue
+		}
+		directives, err := getDirectives(def.Extensions)
+		if err != nil {
+			return 0, err
+		}
+		if w, ok := directiveWeight(directives); ok {
+			cost = addCost(cost, w)
+		}
+		cost = addCost(cost, c.inputCost(def.Type, value))
+	}
+	return cost, nil
+}
+
+// inputCost adds the @cost weights of the fields set on an input object value
+func (c *costCalculator) inputCost(t graphql.Input, value interface{}) int {
+	input, ok := graphql.GetNamed(t).(*graphql.InputObject)
+	if !ok {
+		return 0
+	}
+
+	cost := 0
+	switch value := value.(type) {
+	case []interface{}:
+		for _, item := range value {
+			cost = addCost(cost, c.inputCost(input, item))
+		}
+	case map[string]interface{}:
+		for name, field := range input.Fields() {
+			fieldValue, ok := value[name]
+			if !ok || fieldValue == nil {
+				continue
+			}
+			directives, _ := getDirectives(field.Extensions)
+			if w, ok := directiveWeight(directives); ok {
+				cost = addCost(cost, w)
+			}
+			cost = addCost(cost, c.inputCost(field.Type, fieldValue))
+		}
+	}
+	return cost
+}
+
+// listSize estimates the size of a list from its slicing arguments, assumed
+// size or the default. Slicing arguments that aren't positive, like
+// first: -1, don't bound the list and fall back to the assumed size.
+func (c *costCalculator) listSize(listSize *DirectiveValue, args map[string]interface{}) int {
+	if listSize == nil {
+		return c.defaultListSize
+	}
+
+	size := 0
+	for _, name := range stringList(listSize.Values["slicingArguments"]) {
+		if n, ok := intValue(args[name]); ok && n > size {
+			size = n
+		}
+	}
+	if size > 0 {
+		return size
+	}
+	if n, ok := intValue(listSize.Values["assumedSize"]); ok && n > 0 {
+		return n
+	}
+	return c.defaultListSize
+}
+
+// argumentValue resolves an argument literal, substituting variables
+func (c *costCalculator) argumentValue(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.Variable:
+		return c.variables[value.Name.Value]
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(value.Values))
+		for _, v := range value.Values {
+			list = append(list, c.argumentValue(v))
+		}
+		return list
+	case *ast.ObjectValue:
+		obj := make(map[string]interface{}, len(value.Fields))
+		for _, field := range value.Fields {
+			obj[field.Name.Value] = c.argumentValue(field.Value)
+		}
+		return obj
+	default:
+		return valueFromAST(value)
+	}
+}
+
+func directiveWeight(directives []*DirectiveValue) (int, bool) {
+	for _, d := range findDirectives(directives, CostDirective) {
+		if w, ok := intValue(d.Values["weight"]); ok {
+			return w, true
+		}
+	}
+	return 0, false
+}
+
+func isCompositeType(t graphql.Type) bool {
+	switch t.(type) {
+	case *graphql.Object, *graphql.Interface, *graphql.Union:
+		return true
+	}
+	return false
+}
+
+func isListType(t graphql.Type) bool {
+	switch t := t.(type) {
+	case *graphql.List:
+		return true
+	case *graphql.NonNull:
+		return isListType(t.OfType)
+	}
+	return false
+}
+
+func intValue(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float64:
+		// JSON variables are floats, out of range values saturate
+		if v >= math.MaxInt {
+			return math.MaxInt, true
+		}
+		if v <= math.MinInt {
+			return math.MinInt, true
+		}
+		return int(v), true
+	}
+	return 0, false
+}
+
+// addCost adds two costs, saturating at math.MaxInt so a huge operation
+// can't wrap around to a cost under the maximum
+func addCost(a, b int) int {
+	if b > 0 && a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// mulCost multiplies a list size by the cost of its items, saturating at
+// math.MaxInt
+func mulCost(size, cost int) int {
+	if size <= 0 || cost <= 0 {
+		return size * cost
+	}
+	if size > math.MaxInt/cost {
+		return math.MaxInt
+	}
+	return size * cost
+}
+
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package gofed
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildCostFederation() *Federation {
+
+	var reviewType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Review",
+			Fields: graphql.Fields{
+				"body": &graphql.Field{
+					Type: graphql.String,
+				},
+			},
+		},
+	)
+
+	var productType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Product",
+			Fields: graphql.Fields{
+				"upc": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"price": &graphql.Field{
+					Type:       graphql.Int,
+					Extensions: Directives(Cost(3)),
+				},
+				"reviews": &graphql.Field{
+					Type: graphql.NewList(reviewType),
+				},
+			},
+			Extensions: upcKey(),
+		},
+	)
+
+	fed := NewFederation()
+	fed.SetMaxCost(50)
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"products": &graphql.Field{
+			Type: graphql.NewList(productType),
+			Args: graphql.FieldConfigArgument{
+				"first": &graphql.ArgumentConfig{
+					Type: graphql.Int,
+				},
+			},
+			Extensions: Directives(ListSize(ListSizeConfig{
+				AssumedSize:      5,
+				SlicingArguments: []string{"first"},
+			})),
+		},
+	}, nil)
+
+	return fed
+}
+
+func TestCostDirectivesSDL(t *testing.T) {
+
+	fed := buildCostFederation()
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`  @link(url: "https://specs.apollo.dev/federation/v2.9", import: ["@cost", "@key", "@listSize"])`,
+		`  price: Int @cost(weight: 3)`,
+		`  products(first: Int): [Product] @listSize(assumedSize: 5, slicingArguments: ["first"])`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+
+	fed := buildCostFederation()
+
+	tests := []struct {
+		query     string
+		variables map[string]interface{}
+		expected  int
+	}{
+		// products: 2 * (1 + price 3 + reviews 10 * 1)
+		{`{ products(first: 2) { upc price reviews { body } } }`, nil, 28},
+		{`query ($n: Int) { products(first: $n) { upc price reviews { body } } }`, map[string]interface{}{"n": 3}, 42},
+		// no slicing argument uses the assumed size
+		{`{ products { ...ProductFields } } fragment ProductFields on Product { upc price reviews { body } }`, nil, 70},
+		{`{ products(first: 1) { __typename upc } }`, nil, 1},
+		// slicing arguments that aren't positive fall back to the assumed size
+		{`{ products(first: 0) { upc price reviews { body } } }`, nil, 70},
+		{`query ($n: Int) { products(first: $n) { upc price reviews { body } } }`, map[string]interface{}{"n": -1}, 70},
+		// huge sizes saturate instead of overflowing
+		{`query ($n: Int) { products(first: $n) { upc price reviews { body } } }`, map[string]interface{}{"n": math.MaxInt64 / 2}, math.MaxInt},
+		{`query ($n: Int) { products(first: $n) { upc } }`, map[string]interface{}{"n": 1e300}, math.MaxInt},
+	}
+	for _, test := range tests {
+		cost, err := fed.EstimateCost(test.query, "", test.variables)
+		if err != nil {
+			t.Errorf("error estimating cost of %s: %s", test.query, err)
+			continue
+		}
+		if cost != test.expected {
+			t.Errorf("cost of %s is %d, expected %d", test.query, cost, test.expected)
+		}
+	}
+
+	if err := fed.CheckCost(`{ products(first: 2) { upc price reviews { body } } }`, "", nil); err != nil {
+		t.Errorf("unexpected cost error: %s", err)
+	}
+	if err := fed.CheckCost(`{ products { upc price reviews { body } } }`, "", nil); !errors.Is(err, ErrCostExceeded) {
+		t.Errorf("expected cost exceeded error, got: %v", err)
+	}
+	huge := map[string]interface{}{"n": math.MaxInt64 / 2}
+	if err := fed.CheckCost(`query ($n: Int) { a: products(first: $n) { reviews { body } } b: products(first: $n) { reviews { body } } }`, "", huge); !errors.Is(err, ErrCostExceeded) {
+		t.Errorf("expected cost exceeded error for huge sizes, got: %v", err)
+	}
+}
+
+func TestEstimateCostFragmentCycle(t *testing.T) {
+
+	fed := buildCostFederation()
+	query := `{ products { ...A } } fragment A on Product { upc ...B } fragment B on Product { price ...A }`
+	if _, err := fed.EstimateCost(query, "", nil); err == nil || !strings.Contains(err.Error(), `Cannot spread fragment "A" within itself`) {
+		t.Errorf("expected fragment cycle error, got %v", err)
+	}
+	query = `{ products { upc weight } }`
+	if _, err := fed.EstimateCost(query, "", nil); err == nil || !strings.Contains(err.Error(), `invalid operation: Cannot query field "weight"`) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+
+	// the same fragment spread twice side by side isn't a cycle
+	query = `{ products(first: 1) { ...A ...A } } fragment A on Product { upc }`
+	if _, err := fed.EstimateCost(query, "", nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// fragments spreading the next one twice are scored once each, not 2^40 times
+	var b strings.Builder
+	b.WriteString(`{ products(first: 1) { ...F0 } }`)
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, ` fragment F%d on Product { price ...F%d ...F%d }`, i, i+1, i+1)
+	}
+	b.WriteString(` fragment F40 on Product { price }`)
+	// Product 1, plus 2^41 - 1 price selections of weight 3
+	if cost, err := fed.EstimateCost(b.String(), "", nil); err != nil || cost != 1+3*(1<<41-1) {
+		t.Errorf("unexpected cost %d, error %v", cost, err)
+	}
+}
@@ -0,0 +1,613 @@
+package gofed
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// federation directive names
+const (
+	KeyDirective             = "key"
+	ExternalDirective        = "external"
+	RequiresDirective        = "requires"
+	ProvidesDirective        = "provides"
+	ExtendsDirective         = "extends"
+	ShareableDirective       = "shareable"
+	InaccessibleDirective    = "inaccessible"
+	OverrideDirective        = "override"
+	TagDirective             = "tag"
+	InterfaceObjectDirective = "interfaceObject"
+	AuthenticatedDirective   = "authenticated"
+	RequiresScopesDirective  = "requiresScopes"
+	PolicyDirective          = "policy"
+	ContextDirective         = "context"
+	FromContextDirective     = "fromContext"
+)
+
+const federationSpecURL = "https://specs.apollo.dev/federation/v"
+
+// federation 1 directives don't require a @link to the federation spec
+const federationV1 = "1.0"
+
+// directiveDefinition describes a federation directive gofed knows how to validate and print
+type directiveDefinition struct {
+	name       string
+	args       []string // argument names in printing order
+	required   []string
+	locations  []string
+	repeatable bool
+	version    string // first federation version supporting the directive
+	// locations needing a later federation version than the directive itself
+	locationVersions map[string]string
+	// arguments needing a later federation version than the directive itself
+	argVersions map[string]string
+	boolArgs    []string
+}
+
+var federationDirectives = map[string]*directiveDefinition{
+	KeyDirective: {
+		name:       KeyDirective,
+		args:       []string{"fields", "resolvable"},
+		required:   []string{"fields"},
+		locations:  []string{graphql.DirectiveLocationObject, graphql.DirectiveLocationInterface},
+		repeatable: true,
+		version:    federationV1,
+		locationVersions: map[string]string{
+			graphql.DirectiveLocationInterface: "2.3",
+		},
+		argVersions: map[string]string{
+			"resolvable": "2.0",
+		},
+		boolArgs: []string{"resolvable"},
+	},
+	ExternalDirective: {
+		name:      ExternalDirective,
+		locations: []string{graphql.DirectiveLocationObject, graphql.DirectiveLocationFieldDefinition},
+		version:   federationV1,
+	},
+	RequiresDirective: {
+		name:      RequiresDirective,
+		args:      []string{"fields"},
+		required:  []string{"fields"},
+		locations: []string{graphql.DirectiveLocationFieldDefinition},
+		version:   federationV1,
+	},
+	ProvidesDirective: {
+		name:      ProvidesDirective,
+		args:      []string{"fields"},
+		required:  []string{"fields"},
+		locations: []string{graphql.DirectiveLocationFieldDefinition},
+		version:   federationV1,
+	},
+	ExtendsDirective: {
+		name:      ExtendsDirective,
+		locations: []string{graphql.DirectiveLocationObject, graphql.DirectiveLocationInterface},
+		version:   federationV1,
+	},
+	ShareableDirective: {
+		name:       ShareableDirective,
+		locations:  []string{graphql.DirectiveLocationObject, graphql.DirectiveLocationFieldDefinition},
+		repeatable: true,
+		version:    "2.0",
+	},
+	InaccessibleDirective: {
+		name: InaccessibleDirective,
+		locations: []string{
+			graphql.DirectiveLocationFieldDefinition,
+			graphql.DirectiveLocationObject,
+			graphql.DirectiveLocationInterface,
+			graphql.DirectiveLocationUnion,
+			graphql.DirectiveLocationArgumentDefinition,
+			graphql.DirectiveLocationScalar,
+			graphql.DirectiveLocationEnum,
+			graphql.DirectiveLocationEnumValue,
+			graphql.DirectiveLocationInputObject,
+			graphql.DirectiveLocationInputFieldDefinition,
+		},
+		version: "2.0",
+	},
+	OverrideDirective: {
+		name:      OverrideDirective,
+		args:      []string{"from", "label"},
+		required:  []string{"from"},
+		locations: []string{graphql.DirectiveLocationFieldDefinition},
+		version:   "2.0",
+		argVersions: map[string]string{
+			"label": "2.7",
+		},
+	},
+	TagDirective: {
+		name:     TagDirective,
+		args:     []string{"name"},
+		required: []string{"name"},
+		locations: []string{
+			graphql.DirectiveLocationFieldDefinition,
+			graphql.DirectiveLocationObject,
+			graphql.DirectiveLocationInterface,
+			graphql.DirectiveLocationUnion,
+			graphql.DirectiveLocationArgumentDefinition,
+			graphql.DirectiveLocationScalar,
+			graphql.DirectiveLocationEnum,
+			graphql.DirectiveLocationEnumValue,
+			graphql.DirectiveLocationInputObject,
+			graphql.DirectiveLocationInputFieldDefinition,
+		},
+		repeatable: true,
+		version:    "2.0",
+	},
+	InterfaceObjectDirective: {
+		name:      InterfaceObjectDirective,
+		locations: []string{graphql.DirectiveLocationObject},
+		version:   "2.3",
+	},
+	AuthenticatedDirective: {
+		name:      AuthenticatedDirective,
+		locations: authLocations,
+		version:   "2.5",
+	},
+	RequiresScopesDirective: {
+		name:      RequiresScopesDirective,
+		args:      []string{"scopes"},
+		required:  []string{"scopes"},
+		locations: authLocations,
+		version:   "2.5",
+	},
+	PolicyDirective: {
+		name:      PolicyDirective,
+		args:      []string{"policies"},
+		required:  []string{"policies"},
+		locations: authLocations,
+		version:   "2.6",
+	},
+	ContextDirective: {
+		name:     ContextDirective,
+		args:     []string{"name"},
+		required: []string{"name"},
+		locations: []string{
+			graphql.DirectiveLocationObject,
+			graphql.DirectiveLocationInterface,
+			graphql.DirectiveLocationUnion,
+		},
+		repeatable: true,
+		version:    "2.8",
+	},
+	FromContextDirective: {
+		name:      FromContextDirective,
+		args:      []string{"field"},
+		required:  []string{"field"},
+		locations: []string{graphql.DirectiveLocationArgumentDefinition},
+		version:   "2.8",
+	},
+	CostDirective: {
+		name:     CostDirective,
+		args:     []string{"weight"},
+		required: []string{"weight"},
+		locations: []string{
+			graphql.DirectiveLocationArgumentDefinition,
+			graphql.DirectiveLocationEnum,
+			graphql.DirectiveLocationFieldDefinition,
+			graphql.DirectiveLocationInputFieldDefinition,
+			graphql.DirectiveLocationObject,
+			graphql.DirectiveLocationScalar,
+		},
+		version: "2.9",
+	},
+	ListSizeDirective: {
+		name:      ListSizeDirective,
+		args:      []string{"assumedSize", "slicingArguments", "sizedFields", "requireOneSlicingArgument"},
+		locations: []string{graphql.DirectiveLocationFieldDefinition},
+		boolArgs:  []string{"requireOneSlicingArgument"},
+		version:   "2.9",
+	},
+}
+
+// the authorization directives share their locations
+var authLocations = []string{
+	graphql.DirectiveLocationFieldDefinition,
+	graphql.DirectiveLocationObject,
+	graphql.DirectiveLocationInterface,
+	graphql.DirectiveLocationScalar,
+	graphql.DirectiveLocationEnum,
+}
+
+// Directives builds an extensions map holding the given directives, ready to
+// be used as the Extensions of a graphql-go type, field, argument or enum value
+func Directives(directives ...*DirectiveValue) map[string]interface{} {
+	return map[string]interface{}{
+		"directives": directives,
+	}
+}
+
+// MergeDirectives returns a copy of extensions with directives appended to the
+// directives it already holds, other extensions are kept. A directives value
+// that isn't a directive list is kept as is, so building the schema reports it.
+func MergeDirectives(extensions map[string]interface{}, directives ...*DirectiveValue) map[string]interface{} {
+	merged := make(map[string]interface{}, len(extensions)+1)
+	for k, v := range extensions {
+		merged[k] = v
+	}
+	existing, err := getDirectives(extensions)
+	if err != nil {
+		return merged
+	}
+	all := make([]*DirectiveValue, 0, len(existing)+len(directives))
+	all = append(all, existing...)
+	merged["directives"] = append(all, directives...)
+	return merged
+}
+
+// KeyOption sets an optional argument of a @key directive
+type KeyOption func(d *DirectiveValue)
+
+// Resolvable sets whether this subgraph can resolve the entity by the key, a
+// subgraph only referencing an entity uses Resolvable(false)
+func Resolvable(resolvable bool) KeyOption {
+	return func(d *DirectiveValue) {
+		d.Values["resolvable"] = resolvable
+	}
+}
+
+// Key makes an object or interface an entity identified by the given field set
+func Key(fields string, options ...KeyOption) *DirectiveValue {
+	d := &DirectiveValue{
+		Name: KeyDirective,
+		Values: map[string]interface{}{
+			"fields": fields,
+		},
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// Requires makes the gateway fetch the given external fields before resolving a field
+func Requires(fields string) *DirectiveValue {
+	return &DirectiveValue{
+		Name: RequiresDirective,
+		Values: map[string]interface{}{
+			"fields": fields,
+		},
+	}
+}
+
+// Provides marks the given fields of the returned entity as resolvable by this subgraph
+func Provides(fields string) *DirectiveValue {
+	return &DirectiveValue{
+		Name: ProvidesDirective,
+		Values: map[string]interface{}{
+			"fields": fields,
+		},
+	}
+}
+
+// Extends marks an object or interface as an extension of a type owned by
+// another federation 1 subgraph
+func Extends() *DirectiveValue {
+	return &DirectiveValue{Name: ExtendsDirective}
+}
+
+// External marks a field as owned by another subgraph
+func External() *DirectiveValue {
+	return &DirectiveValue{Name: ExternalDirective}
+}
+
+// Shareable marks an object or field as resolvable by more than one subgraph
+func Shareable() *DirectiveValue {
+	return &DirectiveValue{Name: ShareableDirective}
+}
+
+// Inaccessible hides a schema element from the supergraph API
+func Inaccessible() *DirectiveValue {
+	return &DirectiveValue{Name: InaccessibleDirective}
+}
+
+// Override moves resolution of a field from the subgraph named from to this one
+func Override(from string) *DirectiveValue {
+	return &DirectiveValue{
+		Name: OverrideDirective,
+		Values: map[string]interface{}{
+			"from": from,
+		},
+	}
+}
+
+// OverrideLabel is like Override but only applies when label is active in the router
+func OverrideLabel(from, label string) *DirectiveValue {
+	d := Override(from)
+	d.Values["label"] = label
+	return d
+}
+
+// Tag attaches a named tag to a schema element, used by contracts
+func Tag(name string) *DirectiveValue {
+	return &DirectiveValue{
+		Name: TagDirective,
+		Values: map[string]interface{}{
+			"name": name,
+		},
+	}
+}
+
+// InterfaceObject marks an object as the local view of an entity interface
+// defined in another subgraph
+func InterfaceObject() *DirectiveValue {
+	return &DirectiveValue{Name: InterfaceObjectDirective}
+}
+
+// getDirectives returns the directives stored in a graphql-go extensions map
+func getDirectives(extensions map[string]interface{}) ([]*DirectiveValue, error) {
+	value, ok := extensions["directives"]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	directives, ok := value.([]*DirectiveValue)
+	if !ok {
+		return nil, fmt.Errorf("directives extension has invalid type %T", value)
+	}
+	return directives, nil
+}
+
+// findDirectives returns every directive named name in the given directive list
+func findDirectives(directives []*DirectiveValue, name string) []*DirectiveValue {
+	found := make([]*DirectiveValue, 0, len(directives))
+	for _, d := range directives {
+		if d != nil && d.Name == name {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// withoutDirective returns the directive list with every directive named name removed
+func withoutDirective(directives []*DirectiveValue, name string) []*DirectiveValue {
+	kept := make([]*DirectiveValue, 0, len(directives))
+	for _, d := range directives {
+		if d != nil && d.Name != name {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// hasDirective reports if a directive named name is in the given directive list
+func hasDirective(directives []*DirectiveValue, name string) bool {
+	return len(findDirectives(directives, name)) > 0
+}
+
+// directiveSite is a single schema element that can carry directives
+type directiveSite struct {
+	location   string
+	path       string
+	directives []*DirectiveValue
+}
+
+// collectDirectiveSites walks every user defined element of the schema and
+// gathers the directives applied to it, sorted by path
+func collectDirectiveSites(schema *graphql.Schema) ([]*directiveSite, error) {
+	sites := make([]*directiveSite, 0, 32)
+
+	add := func(location, path string, extensions map[string]interface{}) error {
+		directives, err := getDirectives(extensions)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		if len(directives) > 0 {
+			sites = append(sites, &directiveSite{location: location, path: path, directives: directives})
+		}
+		return nil
+	}
+
+	addFields := func(parent string, fields graphql.FieldDefinitionMap) error {
+		for _, field := range sortFields(fields) {
+			path := parent + "." + field.Name
+			if err := add(graphql.DirectiveLocationFieldDefinition, path, field.Extensions); err != nil {
+				return err
+			}
+			for _, arg := range field.Args {
+				argPath := fmt.Sprintf("%s(%s:)", path, arg.Name())
+				if err := add(graphql.DirectiveLocationArgumentDefinition, argPath, arg.Extensions); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, t := range sortTypeMap(schema.TypeMap()) {
+		var err error
+		switch t := t.(type) {
+		case *graphql.Object:
+			if err = add(graphql.DirectiveLocationObject, t.Name(), t.Extensions()); err == nil {
+				err = addFields(t.Name(), t.Fields())
+			}
+		case *graphql.Interface:
+			if err = add(graphql.DirectiveLocationInterface, t.Name(), t.Extensions()); err == nil {
+				err = addFields(t.Name(), t.Fields())
+			}
+		case *graphql.Union:
+			err = add(graphql.DirectiveLocationUnion, t.Name(), t.Extensions())
+		case *graphql.Enum:
+			if err = add(graphql.DirectiveLocationEnum, t.Name(), t.Extensions()); err == nil {
+				for _, v := range sortEnumValues(t.Values()) {
+					if err = add(graphql.DirectiveLocationEnumValue, t.Name()+"."+v.Name, v.Extensions); err != nil {
+						break
+					}
+				}
+			}
+		case *graphql.InputObject:
+			if err = add(graphql.DirectiveLocationInputObject, t.Name(), t.Extensions()); err == nil {
+				for _, field := range sortInputFields(t.Fields()) {
+					path := t.Name() + "." + field.Name()
+					if err = add(graphql.DirectiveLocationInputFieldDefinition, path, field.Extensions); err != nil {
+						break
+					}
+				}
+			}
+		case *graphql.Scalar:
+			err = add(graphql.DirectiveLocationScalar, t.Name(), t.Extensions())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sites, nil
+}
+
+// validateDirectives checks every federation directive in the schema is used
+// in an allowed location with the arguments it expects
+func validateDirectives(sites []*directiveSite) error {
+	for _, site := range sites {
+		seen := make(map[string]bool)
+		for _, d := range site.directives {
+			if d == nil {
+				return fmt.Errorf("%s: nil directive", site.path)
+			}
+			def, ok := federationDirectives[d.Name]
+			if !ok {
+				continue
+			}
+			if !containsString(def.locations, site.location) {
+				return fmt.Errorf("%s: @%s cannot be used on %s", site.path, d.Name, site.location)
+			}
+			if seen[d.Name] && !def.repeatable {
+				return fmt.Errorf("%s: @%s is not repeatable", site.path, d.Name)
+			}
+			seen[d.Name] = true
+			for _, arg := range def.required {
+				if _, ok := d.Values[arg]; !ok {
+					return fmt.Errorf("%s: @%s is missing required argument %q", site.path, d.Name, arg)
+				}
+			}
+			for arg, value := range d.Values {
+				if !containsString(def.args, arg) {
+					return fmt.Errorf("%s: @%s has unknown argument %q", site.path, d.Name, arg)
+				}
+				if _, ok := value.(bool); containsString(def.boolArgs, arg) && !ok {
+					return fmt.Errorf("%s: @%s argument %q must be a Boolean", site.path, d.Name, arg)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateCustomDirectives checks directives declared with AddDirective are
+// used in their declared locations with known arguments
+func validateCustomDirectives(sites []*directiveSite, directives []*graphql.Directive, repeatable map[string]bool) error {
+	declared := make(map[string]*graphql.Directive, len(directives))
+	for _, d := range directives {
+		declared[d.Name] = d
+	}
+
+	for _, site := range sites {
+		seen := make(map[string]bool)
+		for _, d := range site.directives {
+			def, ok := declared[d.Name]
+			if !ok {
+				continue
+			}
+			if !containsString(def.Locations, site.location) {
+				return fmt.Errorf("%s: @%s cannot be used on %s", site.path, d.Name, site.location)
+			}
+			if seen[d.Name] && !repeatable[d.Name] {
+				return fmt.Errorf("%s: @%s is not repeatable", site.path, d.Name)
+			}
+			seen[d.Name] = true
+			for _, arg := range def.Args {
+				_, nonNull := arg.Type.(*graphql.NonNull)
+				if _, ok := d.Values[arg.Name()]; !ok && nonNull && arg.DefaultValue == nil {
+					return fmt.Errorf("%s: @%s is missing required argument %q", site.path, d.Name, arg.Name())
+				}
+			}
+			for arg := range d.Values {
+				if !hasArgument(def.Args, arg) {
+					return fmt.Errorf("%s: @%s has unknown argument %q", site.path, d.Name, arg)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasArgument(args []*graphql.Argument, name string) bool {
+	for _, arg := range args {
+		if arg.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// federationVersion works out the lowest federation version supporting all
+// federation directives used in the schema, along with the import list for @link
+func federationVersion(sites []*directiveSite) (string, []string) {
+	version := federationV1
+	used := make(map[string]bool)
+
+	for _, site := range sites {
+		for _, d := range site.directives {
+			def, ok := federationDirectives[d.Name]
+			if !ok {
+				continue
+			}
+			used[d.Name] = true
+			required := def.version
+			if v, ok := def.locationVersions[site.location]; ok {
+				required = v
+			}
+			for arg := range d.Values {
+				if v, ok := def.argVersions[arg]; ok && compareVersions(v, required) > 0 {
+					required = v
+				}
+			}
+			if compareVersions(required, version) > 0 {
+				version = required
+			}
+		}
+	}
+
+	imports := make([]string, 0, len(used))
+	for name := range used {
+		imports = append(imports, "@"+name)
+	}
+	sort.Strings(imports)
+
+	return version, imports
+}
+
+// compareVersions compares two "major.minor" version strings
+func compareVersions(a, b string) int {
+	aMajor, aMinor := splitVersion(a)
+	bMajor, bMinor := splitVersion(b)
+	switch {
+	case aMajor != bMajor:
+		return aMajor - bMajor
+	default:
+		return aMinor - bMinor
+	}
+}
+
+func splitVersion(v string) (int, int) {
+	parts := strings.SplitN(v, ".", 2)
+	major, _ := strconv.Atoi(parts[0])
+	minor := 0
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	return major, minor
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
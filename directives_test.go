@@ -0,0 +1,165 @@
+package gofed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildFed2Schema(productExtensions map[string]interface{}) *Federation {
+
+	var colorEnum = graphql.NewEnum(graphql.EnumConfig{
+		Name: "Color",
+		Values: graphql.EnumValueConfigMap{
+			"RED": &graphql.EnumValueConfig{
+				Value: "red",
+			},
+			"SECRET": &graphql.EnumValueConfig{
+				Value:      "secret",
+				Extensions: Directives(Inaccessible()),
+			},
+		},
+	})
+
+	var productType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Product",
+			Fields: graphql.Fields{
+				"upc": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"name": &graphql.Field{
+					Type:       graphql.String,
+					Extensions: Directives(Shareable(), Tag("public")),
+				},
+				"price": &graphql.Field{
+					Type:       graphql.Int,
+					Extensions: Directives(Override("legacy")),
+					Args: graphql.FieldConfigArgument{
+						"currency": &graphql.ArgumentConfig{
+							Type:       graphql.String,
+							Extensions: Directives(Tag("internal")),
+						},
+					},
+				},
+				"color": &graphql.Field{
+					Type: colorEnum,
+				},
+			},
+			Extensions: productExtensions,
+		},
+	)
+
+	var queryFields = graphql.Fields{
+		"product": &graphql.Field{
+			Type: productType,
+		},
+	}
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(queryFields, nil)
+
+	return fed
+}
+
+func TestFed2DirectivesSDL(t *testing.T) {
+
+	fed := buildFed2Schema(Directives(
+		&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "upc"}},
+		Shareable(),
+	))
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+
+	expected := []string{
+		`extend schema`,
+		`  @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@inaccessible", "@key", "@override", "@shareable", "@tag"])`,
+		`type Product @key(fields: "upc") @shareable {`,
+		`  name: String @shareable @tag(name: "public")`,
+		`  price(currency: String @tag(name: "internal")): Int @override(from: "legacy")`,
+		`  SECRET @inaccessible`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q", line)
+		}
+	}
+
+	if strings.Contains(sdl, "#### Apollo Federation ####") {
+		t.Error("federation 2 sdl should not contain federation 1 definitions")
+	}
+}
+
+func TestDirectiveLocations(t *testing.T) {
+
+	fed := buildFed2Schema(Directives(
+		&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "upc"}},
+		Override("legacy"),
+	))
+
+	err := fed.Error()
+	if err == nil {
+		t.Fatal("expected error for @override on an object")
+	}
+	if err.Error() != "Product: @override cannot be used on OBJECT" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if fed.Schema() != nil || fed.PrintSDL() != "" {
+		t.Error("a schema that failed to build should not be served")
+	}
+
+	fed = buildFed2Schema(Directives(
+		&DirectiveValue{Name: TagDirective, Values: map[string]interface{}{"label": "public"}},
+	))
+	if err := fed.Error(); err == nil || !strings.Contains(err.Error(), `missing required argument "name"`) {
+		t.Errorf("expected missing argument error, got: %v", err)
+	}
+}
+
+func TestDirectiveConstructors(t *testing.T) {
+
+	extensions := map[string]interface{}{"owner": "catalog", "directives": []*DirectiveValue{Key("upc")}}
+	merged := MergeDirectives(extensions, Shareable(), Tag("public"))
+	if merged["owner"] != "catalog" {
+		t.Errorf("merged extensions lost other values: %v", merged)
+	}
+	if directives, _ := getDirectives(extensions); len(directives) != 1 {
+		t.Errorf("merging changed the original extensions: %v", extensions)
+	}
+
+	fed := buildFed2Schema(merged)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+	if line := `type Product @key(fields: "upc") @shareable @tag(name: "public") {`; !strings.Contains(fed.PrintSDL(), line+"\n") {
+		t.Errorf("sdl is missing line %q:\n%s", line, fed.PrintSDL())
+	}
+
+	fed = buildFed2Schema(MergeDirectives(map[string]interface{}{"directives": Key("upc")}, Shareable()))
+	if err := fed.Error(); err == nil || !strings.Contains(err.Error(), "invalid type") {
+		t.Errorf("expected invalid directives error, got: %v", err)
+	}
+
+	tests := []struct {
+		directive *DirectiveValue
+		printed   string
+	}{
+		{Key("id"), `@key(fields: "id")`},
+		{Key("id", Resolvable(false)), `@key(fields: "id", resolvable: false)`},
+		{Requires("weight"), `@requires(fields: "weight")`},
+		{Provides("name"), `@provides(fields: "name")`},
+	}
+	for _, test := range tests {
+		var out strings.Builder
+		if err := printDirectiveValues(Directives(test.directive), &out); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got := strings.TrimSpace(out.String()); got != test.printed {
+			t.Errorf("expected %s, got %s", test.printed, got)
+		}
+	}
+}
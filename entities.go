@@ -0,0 +1,321 @@
+package gofed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// parseRepresentation matches a raw representation against the keys of the
+// entity named by its __typename
+func (f *Federation) parseRepresentation(raw interface{}) (*Representation, error) {
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: expected an object", ErrInvalidRepresentation)
+	}
+
+	typeName, _ := values["__typename"].(string)
+	if typeName == "" {
+		return nil, fmt.Errorf("%w: missing __typename", ErrInvalidRepresentation)
+	}
+
+	keys, err := f.entityKeys(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &Representation{
+		TypeName: typeName,
+		Values:   make(map[string]interface{}, len(values)),
+	}
+	for k, v := range values {
+		if k != "__typename" {
+			rep.Values[k] = v
+		}
+	}
+
+	// use the first key whose fields are all present in the representation
+	for _, key := range keys {
+		fields := topLevelKeyFields(key)
+		if len(fields) == 0 || !hasAllFields(rep.Values, fields) {
+			continue
+		}
+		if err := f.coerceKeyFields(typeName, key, rep.Values); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRepresentation, err)
+		}
+		rep.KeyName = key
+		if len(fields) == 1 {
+			rep.KeyValue = rep.Values[fields[0]]
+		} else {
+			keyValue := make(map[string]interface{}, len(fields))
+			for _, field := range fields {
+				keyValue[field] = rep.Values[field]
+			}
+			rep.KeyValue = keyValue
+		}
+		return rep, nil
+	}
+
+	// with a single key, report which of its fields is missing
+	if len(keys) == 1 {
+		for _, field := range topLevelKeyFields(keys[0]) {
+			if _, ok := rep.Values[field]; !ok {
+				return nil, fmt.Errorf("%w: missing key field %s.%s", ErrUnmatchedKey, typeName, field)
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnmatchedKey, typeName)
+}
+
+// entityKeys returns the key field sets of the entity object or entity interface named typeName
+func (f *Federation) entityKeys(typeName string) ([]string, error) {
+	if f.schema.Type(typeName) == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTypename, typeName)
+	}
+
+	var extensions map[string]interface{}
+	if obj, ok := f.objects[typeName]; ok {
+		extensions = obj.Extensions()
+	} else if iface := f.entityInterface(typeName); iface != nil {
+		extensions = iface.Extensions()
+	}
+
+	keys, err := keyDirectives(extensions)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: %s has no @key", ErrNotEntity, typeName)
+	}
+
+	keys, err = resolvableKeyDirectives(extensions)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: %s is not resolvable in this subgraph", ErrNotEntity, typeName)
+	}
+	return keys, nil
+}
+
+func (f *Federation) entityInterface(name string) *graphql.Interface {
+	for _, iface := range f.entityInterfaces {
+		if iface.Name() == name {
+			return iface
+		}
+	}
+	return nil
+}
+
+func (f *Federation) entityObject(name string) *graphql.Object {
+	for _, obj := range f.entityType.Types() {
+		if obj.Name() == name {
+			return obj
+		}
+	}
+	return nil
+}
+
+// entityResults is the state an execution shares between the _entities
+// resolver and the _Entity union. graphql-go completes the list items one at
+// a time, so each item records the member its representation resolved to
+// right before the union resolves its type. The errors of the failed
+// representations are added to the result once execution is done.
+type entityResults struct {
+	typeName string
+	errors   []gqlerrors.FormattedError
+}
+
+type entityResultsKey struct{}
+
+// fail reports a representation error at its own _entities[i] path, with
+// the extensions of the EntityError
+func (r *entityResults) fail(info graphql.ResolveInfo, err *EntityError) {
+	path := append(info.Path.AsArray(), err.Index)
+	located := gqlerrors.NewErrorWithPath(err.Error(), graphql.FieldASTsToNodeASTs(info.FieldASTs), "", nil, []int{}, path, err)
+	r.errors = append(r.errors, gqlerrors.FormatError(located))
+}
+
+func (r *entityResults) apply(result *graphql.Result) {
+	if result != nil && len(r.errors) > 0 {
+		result.Errors = append(result.Errors, r.errors...)
+	}
+}
+
+// entityItem returns the _entities item of an entity resolved for typeName,
+// a thunk recording the _Entity member it belongs to
+func (f *Federation) entityItem(results *entityResults, typeName string, entity interface{}, p graphql.ResolveParams) interface{} {
+	if entity == nil || results == nil {
+		return entity
+	}
+
+	concrete := f.entityObject(typeName)
+	if concrete == nil {
+		// typeName is an entity interface, ask it for the implementation
+		if iface := f.entityInterface(typeName); iface != nil && iface.ResolveType != nil {
+			concrete = iface.ResolveType(graphql.ResolveTypeParams{
+				Value:   entity,
+				Info:    p.Info,
+				Context: p.Context,
+			})
+		}
+	}
+	if concrete == nil {
+		return entity
+	}
+
+	name := concrete.Name()
+	return func() (interface{}, error) {
+		results.typeName = name
+		return entity, nil
+	}
+}
+
+// resolveEntityType resolves the concrete _Entity member for an entity value
+func (f *Federation) resolveEntityType(p graphql.ResolveTypeParams) *graphql.Object {
+	if results, ok := p.Context.Value(entityResultsKey{}).(*entityResults); ok && results.typeName != "" {
+		typeName := results.typeName
+		results.typeName = ""
+		return f.entityObject(typeName)
+	}
+
+	if values, ok := p.Value.(map[string]interface{}); ok {
+		if typeName, ok := values["__typename"].(string); ok {
+			return f.entityObject(typeName)
+		}
+	}
+
+	for _, obj := range f.entityType.Types() {
+		if obj.IsTypeOf != nil && obj.IsTypeOf(graphql.IsTypeOfParams{Value: p.Value, Info: p.Info, Context: p.Context}) {
+			return obj
+		}
+	}
+
+	for _, iface := range f.entityInterfaces {
+		if iface.ResolveType == nil {
+			continue
+		}
+		if obj := iface.ResolveType(p); obj != nil && f.entityObject(obj.Name()) != nil {
+			return obj
+		}
+	}
+
+	if types := f.entityType.Types(); len(types) == 1 {
+		return types[0]
+	}
+
+	return nil
+}
+
+// entityExtension gives every execution its entityResults
+type entityExtension struct{}
+
+var _ graphql.Extension = entityExtension{}
+
+func (entityExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (entityExtension) Name() string {
+	return "entities"
+}
+
+func (entityExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (entityExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (entityExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	// graphql.Do leaves the context nil when Params has none
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results := &entityResults{}
+	return context.WithValue(ctx, entityResultsKey{}, results), results.apply
+}
+
+func (entityExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	return ctx, func(interface{}, error) {}
+}
+
+func (entityExtension) HasResult() bool {
+	return false
+}
+
+func (entityExtension) GetResult(context.Context) interface{} {
+	return nil
+}
+
+// validateEntityInterfaces checks that entity interfaces and @interfaceObject
+// types are backed by keys
+func (f *Federation) validateEntityInterfaces() error {
+	for _, iface := range f.entityInterfaces {
+		for _, obj := range sortObjects(f.objects) {
+			if !implements(obj, iface) {
+				continue
+			}
+			keys, err := getKeyDirectives(obj)
+			if err != nil {
+				return err
+			}
+			if len(keys) == 0 {
+				return fmt.Errorf("%s implements entity interface %s but has no @key", obj.Name(), iface.Name())
+			}
+		}
+	}
+
+	for _, obj := range sortObjects(f.objects) {
+		directives, err := getDirectives(obj.Extensions())
+		if err != nil {
+			return err
+		}
+		if hasDirective(directives, InterfaceObjectDirective) && !hasDirective(directives, KeyDirective) {
+			return fmt.Errorf("%s: @interfaceObject requires a @key", obj.Name())
+		}
+	}
+	return nil
+}
+
+func implements(obj *graphql.Object, iface *graphql.Interface) bool {
+	for _, i := range obj.Interfaces() {
+		if i.Name() == iface.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// topLevelKeyFields returns the top level field names of a key field set,
+// nested selections like "org { id }" are reduced to "org"
+func topLevelKeyFields(fields string) []string {
+	names := make([]string, 0, 2)
+	depth := 0
+	for _, token := range strings.Fields(strings.NewReplacer("{", " { ", "}", " } ").Replace(fields)) {
+		switch token {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		default:
+			if depth == 0 {
+				names = append(names, token)
+			}
+		}
+	}
+	return names
+}
+
+func hasAllFields(values map[string]interface{}, fields []string) bool {
+	for _, field := range fields {
+		if _, ok := values[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,343 @@
+package gofed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+type testBook struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type testMovie struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+func mediaKey() map[string]interface{} {
+	return Directives(Key("id"))
+}
+
+func upcKey() map[string]interface{} {
+	return Directives(Key("upc"))
+}
+
+func buildMediaSchema() *Federation {
+
+	var bookType, movieType *graphql.Object
+
+	mediaInterface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Media",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"title": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+		Extensions: mediaKey(),
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			switch p.Value.(type) {
+			case testBook:
+				return bookType
+			case testMovie:
+				return movieType
+			}
+			return nil
+		},
+	})
+
+	mediaFields := graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+		"title": &graphql.Field{
+			Type: graphql.String,
+		},
+	}
+
+	bookType = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Book",
+		Fields:     mediaFields,
+		Interfaces: []*graphql.Interface{mediaInterface},
+		Extensions: mediaKey(),
+	})
+	movieType = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Movie",
+		Fields:     mediaFields,
+		Interfaces: []*graphql.Interface{mediaInterface},
+		Extensions: mediaKey(),
+	})
+
+	var queryFields = graphql.Fields{
+		"book": &graphql.Field{
+			Type: bookType,
+		},
+		"movie": &graphql.Field{
+			Type: movieType,
+		},
+		"media": &graphql.Field{
+			Type: graphql.NewList(mediaInterface),
+		},
+	}
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(queryFields, nil)
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		switch rep.KeyValue {
+		case "b1":
+			return testBook{ID: "b1", Title: "The Hobbit"}, nil
+		case "m1":
+			return testMovie{ID: "m1", Title: "The Two Towers"}, nil
+		}
+		return nil, fmt.Errorf("media not found: %v", rep.KeyValue)
+	})
+
+	return fed
+}
+
+func TestEntityInterfaceResolve(t *testing.T) {
+
+	fed := buildMediaSchema()
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	query := `
+		query ($_representations: [_Any!]!) {
+			_entities(representations: $_representations) {
+				__typename
+				... on Book { id, title }
+				... on Movie { id, title }
+			}
+		}
+	`
+	params := graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: query,
+		VariableValues: map[string]interface{}{
+			"_representations": []interface{}{
+				map[string]interface{}{"__typename": "Media", "id": "m1"},
+				map[string]interface{}{"__typename": "Media", "id": "b1"},
+			},
+		},
+	}
+	r := graphql.Do(params)
+	if len(r.Errors) > 0 {
+		t.Errorf("failed to execute graphql operation, errors: %+v", r.Errors)
+	}
+
+	rJSON, _ := json.Marshal(r)
+	if string(rJSON) != `{"data":{"_entities":[{"__typename":"Movie","id":"m1","title":"The Two Towers"},{"__typename":"Book","id":"b1","title":"The Hobbit"}]}}` {
+		fmt.Fprintln(os.Stdout, string(rJSON))
+		t.Errorf("invalid query results")
+	}
+
+	sdl := fed.PrintSDL()
+	if !strings.Contains(sdl, `@link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key"])`) {
+		t.Errorf("entity interfaces should link federation v2.3:\n%s", sdl)
+	}
+	if !strings.Contains(sdl, `interface Media @key(fields: "id") {`) {
+		t.Errorf("entity interface key not printed:\n%s", sdl)
+	}
+	for _, line := range []string{`type Book implements Media @key(fields: "id") {`, `type Movie implements Media @key(fields: "id") {`} {
+		if !strings.Contains(sdl, line) {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+}
+
+func TestEntitySharedGoType(t *testing.T) {
+
+	// a single Go type backs both entities, the representation picks the type
+	type item struct {
+		ID string `json:"id"`
+	}
+	key := Directives(&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "id"}})
+	fields := graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+	}
+	bookType := graphql.NewObject(graphql.ObjectConfig{Name: "Book", Fields: fields, Extensions: key})
+	movieType := graphql.NewObject(graphql.ObjectConfig{Name: "Movie", Fields: fields, Extensions: key})
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"book":  &graphql.Field{Type: bookType},
+		"movie": &graphql.Field{Type: movieType},
+	}, nil)
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		if rep.KeyValue == "missing" {
+			return nil, ErrEntityNotFound
+		}
+		return item{ID: rep.KeyValue.(string)}, nil
+	})
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	r := graphql.Do(graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: `query ($r: [_Any!]!) { _entities(representations: $r) { __typename ... on Book { id } ... on Movie { id } } }`,
+		VariableValues: map[string]interface{}{
+			"r": []interface{}{
+				map[string]interface{}{"__typename": "Movie", "id": "m1"},
+				map[string]interface{}{"__typename": "Book", "id": "missing"},
+				map[string]interface{}{"__typename": "Book", "id": "b1"},
+				map[string]interface{}{"__typename": "Movie", "id": "m2"},
+			},
+		},
+	})
+	rJSON, _ := json.Marshal(r)
+	expected := `{"data":{"_entities":[{"__typename":"Movie","id":"m1"},null,{"__typename":"Book","id":"b1"},{"__typename":"Movie","id":"m2"}]},` +
+		`"errors":[{"message":"representation 1: entity not found","locations":[{"line":1,"column":24}],"path":["_entities",1],"extensions":{"code":"ENTITY_NOT_FOUND"}}]}`
+	if string(rJSON) != expected {
+		t.Errorf("unexpected result:\n%s", rJSON)
+	}
+}
+
+func TestEntityInterfaceInvalidDirectives(t *testing.T) {
+
+	mediaInterface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Media",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		},
+		Extensions: map[string]interface{}{"directives": "@key(fields: \"id\")"},
+	})
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"media": &graphql.Field{
+			Type: mediaInterface,
+		},
+	}, nil)
+	if err := fed.Error(); err == nil || err.Error() != "Media: key directive has invalid type" {
+		t.Errorf("expected invalid directives error, got %v", err)
+	}
+}
+
+func TestInterfaceObject(t *testing.T) {
+
+	mediaType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Media",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"reviews": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
+			},
+		},
+		Extensions: Directives(
+			&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "id"}},
+			InterfaceObject(),
+		),
+	})
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"topMedia": &graphql.Field{
+			Type: mediaType,
+		},
+	}, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+	if !strings.Contains(sdl, `type Media @key(fields: "id") @interfaceObject {`) {
+		t.Errorf("interface object not printed:\n%s", sdl)
+	}
+	if !strings.Contains(sdl, `import: ["@interfaceObject", "@key"]`) {
+		t.Errorf("interface object not imported:\n%s", sdl)
+	}
+
+	// @interfaceObject without a key is rejected
+	fed = NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"topMedia": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Media",
+				Fields: graphql.Fields{
+					"id": &graphql.Field{
+						Type: graphql.ID,
+					},
+				},
+				Extensions: Directives(InterfaceObject()),
+			}),
+		},
+	}, nil)
+	if err := fed.Error(); err == nil || err.Error() != "Media: @interfaceObject requires a @key" {
+		t.Errorf("expected missing key error, got: %v", err)
+	}
+}
+
+func TestNonResolvableKey(t *testing.T) {
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		},
+		Extensions: Directives(&DirectiveValue{
+			Name:   KeyDirective,
+			Values: map[string]interface{}{"fields": "id", "resolvable": false},
+		}),
+	})
+
+	reviewType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Review",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"author": &graphql.Field{
+				Type: userType,
+			},
+		},
+		Extensions: mediaKey(),
+	})
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"reviews": &graphql.Field{
+			Type: graphql.NewList(reviewType),
+		},
+	}, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	if types := fed.entityType.Types(); len(types) != 1 || types[0].Name() != "Review" {
+		t.Errorf("unexpected entity types %v", types)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`type User @key(fields: "id", resolvable: false) {`,
+		`  @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key"])`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+
+	if _, err := fed.parseRepresentation(map[string]interface{}{"__typename": "User", "id": "1"}); !errors.Is(err, ErrNotEntity) ||
+		err.Error() != "not an entity: User is not resolvable in this subgraph" {
+		t.Errorf("expected non resolvable error, got: %v", err)
+	}
+}
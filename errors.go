@@ -0,0 +1,111 @@
+package gofed
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// Errors reported for representations sent to _entities. They are wrapped in
+// an EntityError, match with errors.Is and map to a stable extensions.code.
+var (
+	// ErrInvalidRepresentation is a representation that isn't an object, has
+	// no __typename or holds key values not matching the key field types
+	ErrInvalidRepresentation = errors.New("invalid representation")
+	// ErrUnknownTypename is a __typename not defined in the subgraph schema
+	ErrUnknownTypename = errors.New("unknown __typename")
+	// ErrNotEntity is a __typename that isn't an entity resolvable by this subgraph
+	ErrNotEntity = errors.New("not an entity")
+	// ErrUnmatchedKey is a representation without the fields of any @key of its type
+	ErrUnmatchedKey = errors.New("no @key matches the representation")
+	// ErrNoEntityResolver is returned when neither SetEntityResolver nor
+	// SetEntityBatchResolver was called
+	ErrNoEntityResolver = errors.New("no entity resolver set")
+	// ErrEntityNotFound can be returned, wrapped or not, by entity resolvers
+	// when no entity matches the representation
+	ErrEntityNotFound = errors.New("entity not found")
+)
+
+// extensions.code of entity resolver errors not wrapping one of the errors above
+const entityResolutionCode = "ENTITY_RESOLUTION_FAILED"
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrInvalidRepresentation, "INVALID_REPRESENTATION"},
+	{ErrUnknownTypename, "UNKNOWN_TYPENAME"},
+	{ErrNotEntity, "NOT_AN_ENTITY"},
+	{ErrUnmatchedKey, "UNMATCHED_KEY"},
+	{ErrNoEntityResolver, "ENTITY_RESOLVER_MISSING"},
+	{ErrEntityNotFound, "ENTITY_NOT_FOUND"},
+}
+
+// EntityError is the error of a single representation. Its entity is null in
+// the _entities list and the error is reported at the path
+// ["_entities", Index].
+type EntityError struct {
+	// Index of the representation in the representations argument
+	Index int
+	// TypeName is the __typename of the representation, if it had one
+	TypeName string
+	Err      error
+	masked   bool
+}
+
+func (e *EntityError) Error() string {
+	if e.masked {
+		return fmt.Sprintf("representation %d: %s", e.Index, e.maskedMessage())
+	}
+	return fmt.Sprintf("representation %d: %s", e.Index, e.Err)
+}
+
+func (e *EntityError) Unwrap() error {
+	return e.Err
+}
+
+// Code returns the stable extensions.code of the error
+func (e *EntityError) Code() string {
+	for _, c := range errorCodes {
+		if errors.Is(e.Err, c.err) {
+			return c.code
+		}
+	}
+	return entityResolutionCode
+}
+
+// Extensions implements gqlerrors.ExtendedError, extensions set by the
+// wrapped error itself take precedence
+func (e *EntityError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code()}
+	var extended gqlerrors.ExtendedError
+	if errors.As(e.Err, &extended) {
+		for k, v := range extended.Extensions() {
+			extensions[k] = v
+		}
+	}
+	return extensions
+}
+
+// maskedMessage is the generic message used in place of the error details
+func (e *EntityError) maskedMessage() string {
+	for _, c := range errorCodes {
+		if errors.Is(e.Err, c.err) {
+			return c.err.Error()
+		}
+	}
+	return "entity could not be resolved"
+}
+
+// SetMaskErrors replaces the messages of _entities errors with generic ones,
+// so resolver and validation details don't leak in production. The
+// extensions.code and path of each error are kept.
+func (f *Federation) SetMaskErrors(mask bool) {
+	f.maskErrors = mask
+}
+
+// entityError wraps the error of the representation at index
+func (f *Federation) entityError(index int, typeName string, err error) *EntityError {
+	return &EntityError{Index: index, TypeName: typeName, Err: err, masked: f.maskErrors}
+}
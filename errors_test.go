@@ -0,0 +1,101 @@
+package gofed
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestEntityErrorCodes(t *testing.T) {
+
+	var reps []*Representation
+	fed := buildRepresentationFederation(&reps)
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		switch rep.KeyValue {
+		case "404":
+			return nil, fmt.Errorf("store %s: %w", rep.KeyValue, ErrEntityNotFound)
+		case "500":
+			return nil, fmt.Errorf("database is down")
+		}
+		return map[string]interface{}{"id": rep.KeyValue}, nil
+	})
+
+	representations := []interface{}{
+		"{}",
+		map[string]interface{}{"id": "1"},
+		map[string]interface{}{"__typename": "Unknown", "id": "1"},
+		map[string]interface{}{"__typename": "Region", "code": "EU"},
+		map[string]interface{}{"__typename": "Store", "number": 1},
+		map[string]interface{}{"__typename": "Store", "id": true},
+		map[string]interface{}{"__typename": "Store", "id": "404"},
+		map[string]interface{}{"__typename": "Store", "id": "500"},
+	}
+	expected := []struct {
+		code    string
+		message string
+		masked  string
+	}{
+		{"INVALID_REPRESENTATION", "representation 0: invalid representation: missing __typename", "representation 0: invalid representation"},
+		{"INVALID_REPRESENTATION", "representation 1: invalid representation: missing __typename", "representation 1: invalid representation"},
+		{"UNKNOWN_TYPENAME", `representation 2: unknown __typename "Unknown"`, "representation 2: unknown __typename"},
+		{"NOT_AN_ENTITY", "representation 3: not an entity: Region has no @key", "representation 3: not an entity"},
+		{"UNMATCHED_KEY", "representation 4: no @key matches the representation: Store", "representation 4: no @key matches the representation"},
+		{"INVALID_REPRESENTATION", "representation 5: invalid representation: Store.id: invalid ID value true", "representation 5: invalid representation"},
+		{"ENTITY_NOT_FOUND", "representation 6: store 404: entity not found", "representation 6: entity not found"},
+		{"ENTITY_RESOLUTION_FAILED", "representation 7: database is down", "representation 7: entity could not be resolved"},
+	}
+
+	for _, mask := range []bool{false, true} {
+		fed.SetMaskErrors(mask)
+		r := graphql.Do(graphql.Params{
+			Schema:         *fed.Schema(),
+			RequestString:  `query ($r: [_Any!]!) { _entities(representations: $r) { ... on Store { id } } }`,
+			VariableValues: map[string]interface{}{"r": representations},
+		})
+		if len(r.Errors) != len(expected) {
+			t.Fatalf("expected %d errors, got %v", len(expected), r.Errors)
+		}
+		for i, e := range expected {
+			message := e.message
+			if mask {
+				message = e.masked
+			}
+			if r.Errors[i].Message != message || r.Errors[i].Extensions["code"] != e.code {
+				t.Errorf("representation %d: unexpected error %q %v", i, r.Errors[i].Message, r.Errors[i].Extensions)
+			}
+		}
+	}
+
+	fed.SetEntityResolver(nil)
+	fed.SetMaskErrors(false)
+	r := graphql.Do(graphql.Params{
+		Schema:         *fed.Schema(),
+		RequestString:  `query ($r: [_Any!]!) { _entities(representations: $r) { ... on Store { id } } }`,
+		VariableValues: map[string]interface{}{"r": []interface{}{map[string]interface{}{"__typename": "Store", "id": "1"}}},
+	})
+	if len(r.Errors) != 1 || r.Errors[0].Extensions["code"] != "ENTITY_RESOLVER_MISSING" {
+		t.Errorf("unexpected errors %v", r.Errors)
+	}
+}
+
+func TestEntityErrorMatching(t *testing.T) {
+
+	var reps []*Representation
+	fed := buildRepresentationFederation(&reps)
+
+	_, err := fed.parseRepresentation(map[string]interface{}{"__typename": "Store", "id": false})
+	if !errors.Is(err, ErrInvalidRepresentation) {
+		t.Errorf("expected ErrInvalidRepresentation, got %v", err)
+	}
+
+	var wrapped error = fed.entityError(3, "Store", fmt.Errorf("lookup failed: %w", ErrEntityNotFound))
+	var entityErr *EntityError
+	if !errors.As(wrapped, &entityErr) || entityErr.Index != 3 || entityErr.TypeName != "Store" {
+		t.Errorf("expected an EntityError, got %v", wrapped)
+	}
+	if !errors.Is(wrapped, ErrEntityNotFound) || entityErr.Code() != "ENTITY_NOT_FOUND" {
+		t.Errorf("expected ErrEntityNotFound, got %v", wrapped)
+	}
+}
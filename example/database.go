@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 type user struct {
@@ -40,9 +41,12 @@ func databaseFind(typeName, keyName string, keyValue interface{}) (interface{},
 	if typeName == "User" {
 		for _, v := range data {
 			obj := reflect.ValueOf(v)
-			field := reflect.Indirect(obj).FieldByName((keyName))
+			// key names are GraphQL field names, "id" matches the ID field
+			field := reflect.Indirect(obj).FieldByNameFunc(func(name string) bool {
+				return strings.EqualFold(name, keyName)
+			})
 			// handle field not existing on type
-			if field.IsZero() {
+			if !field.IsValid() || field.IsZero() {
 				continue
 			}
 
@@ -1,36 +1,28 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 
-	"github.com/graphql-go/graphql"
 	"github.com/jesse-apollo/gofed"
 )
 
-func executeQuery(query string, schema *graphql.Schema) *graphql.Result {
-	result := graphql.Do(graphql.Params{
-		Schema:        *schema,
-		RequestString: query,
-	})
-	if len(result.Errors) > 0 {
-		fmt.Printf("wrong result, unexpected errors: %v", result.Errors)
-	}
-	return result
-}
-
 func main() {
 
 	fed := gofed.NewFederation()
-	schema := fed.BuildSubgraphSchema(queryFields, nil)
-
-	http.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
-		result := executeQuery(r.URL.Query().Get("query"), schema)
-		json.NewEncoder(w).Encode(result)
+	fed.SetEntityResolver(func(rep *gofed.Representation) (interface{}, error) {
+		return databaseFind(rep.TypeName, rep.KeyName, rep.KeyValue)
 	})
+	fed.BuildSubgraphSchema(queryFields, nil)
+	if err := fed.Error(); err != nil {
+		log.Fatal(err)
+	}
+
+	http.Handle("/graphql", fed.Handler())
 
 	fmt.Println("Now server is running on port 8080")
 	fmt.Println("Test with Get: curl -g 'http://localhost:8080/graphql?query={user(id:\"1\"){name}}'")
-	http.ListenAndServe(":8080", nil)
+	fmt.Println("Test with Post: curl -H 'Content-Type: application/json' -d '{\"query\": \"{user(id:\\\"1\\\"){name}}\"}' http://localhost:8080/graphql")
+	log.Fatal(http.ListenAndServe(":8080", nil))
 }
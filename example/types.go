@@ -16,16 +16,7 @@ var userType = graphql.NewObject(
 				Type: graphql.String,
 			},
 		},
-		Extensions: map[string]interface{}{
-			"directives": []*gofed.DirectiveValue{
-				{
-					Name: "key",
-					Values: map[string]interface{}{
-						"fields": "id",
-					},
-				},
-			},
-		},
+		Extensions: gofed.Directives(gofed.Key("id")),
 	},
 )
 
@@ -3,7 +3,10 @@ package gofed
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
 
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/language/ast"
@@ -18,137 +21,262 @@ type Representation struct {
 	TypeName string
 	KeyName  string
 	KeyValue interface{}
+	// Values holds every field sent in the representation except __typename
+	Values map[string]interface{}
 }
 
 type EntityResolverFn func(rep *Representation) (interface{}, error)
 type EntityResolverBatchFn func(reps []*Representation) ([]interface{}, error)
 
 type Federation struct {
-	entityResolver      EntityResolverFn
-	batchEntityResolver EntityResolverBatchFn
-	schema              *graphql.Schema
-	entityType          *graphql.Union
-	objects             map[string]*graphql.Object
-	interfaces          map[string]*graphql.Interface
+	entityResolver       EntityResolverFn
+	batchEntityResolver  EntityResolverBatchFn
+	schema               *graphql.Schema
+	entityType           *graphql.Union
+	objects              map[string]*graphql.Object
+	interfaces           map[string]*graphql.Interface
+	entityInterfaces     []*graphql.Interface
+	extensionStyle       ExtensionStyle
+	directives           []*graphql.Directive
+	repeatable           map[string]bool
+	composed             []*composedDirective
+	enforceAuthorization bool
+	maxCost              int
+	defaultListSize      int
+	overrideRollout      OverrideRolloutFn
+	wrappedResolvers     bool
+	ownTypes             bool
+	hideInaccessible     bool
+	maskErrors           bool
+	typedResolvers       map[string]typedEntityResolver
+	maxRequestSize       int64
+	fromSDL              bool
+	linkVersion          string         // federation version linked by the SDL the schema was built from
+	types                []graphql.Type // kept in the schema even if unreachable from the root fields
+	structMu             sync.Mutex
+	structObjects        map[reflect.Type]*graphql.Object // built by ObjectFromStruct
+	err                  error
 }
 
 func NewFederation() *Federation {
 	return &Federation{}
 }
 
+// resolveEntity resolves every representation independently. A representation
+// that can't be parsed or resolved becomes null at _entities[i], with an error
+// at that path, while the others still resolve.
 func (f *Federation) resolveEntity(p graphql.ResolveParams) (interface{}, error) {
-	_, isOK := p.Args["representations"].([]map[string]interface{})
+	rawReps, isOK := p.Args["representations"].([]interface{})
 
 	if !isOK {
-		//fmt.Fprintln(os.Stdout, fmt.Sprintf("reps: %v", p.Args["representations"]))
-
-		return nil, fmt.Errorf("invalid representations")
+		return nil, fmt.Errorf("%w: representations must be a list", ErrInvalidRepresentation)
 	}
-
-	return nil, nil
-	/*reps, isOK := p.Args["representations"].([]map[string]interface{})
-
-	if !isOK {
-		return nil, fmt.Errorf("invalid representations")
+	// failed representations are left null and reported with their own path
+	results, _ := p.Context.Value(entityResultsKey{}).(*entityResults)
+	failures := make([]*EntityError, 0)
+	entities := make([]interface{}, len(rawReps))
+	reps := make([]*Representation, 0, len(rawReps))
+	indexes := make([]int, 0, len(rawReps))
+	for i, raw := range rawReps {
+		rep, err := f.parseRepresentation(raw)
+		if err != nil {
+			typeName := ""
+			if values, ok := raw.(map[string]interface{}); ok {
+				typeName, _ = values["__typename"].(string)
+			}
+			failures = append(failures, f.entityError(i, typeName, err))
+			continue
+		}
+		reps = append(reps, rep)
+		indexes = append(indexes, i)
 	}
 
-	var keyValue interface{}
-	var keyName string
-	_ = keyValue
-	_ = keyName
+	// representations of types with a typed resolver are resolved per type,
+	// the others go to the entity resolver
+	resolved := make([]interface{}, len(reps))
+	untyped := make([]int, 0, len(reps))
+	typed := make(map[string][]int)
+	for i, rep := range reps {
+		if _, ok := f.typedResolvers[rep.TypeName]; ok {
+			typed[rep.TypeName] = append(typed[rep.TypeName], i)
+		} else {
+			untyped = append(untyped, i)
+		}
+	}
+	for typeName, positions := range typed {
+		typedReps := make([]*Representation, 0, len(positions))
+		for _, i := range positions {
+			typedReps = append(typedReps, reps[i])
+		}
+		for j, entity := range f.typedResolvers[typeName].resolve(p.Context, typedReps) {
+			resolved[positions[j]] = entity
+		}
+	}
+	if len(untyped) > 0 {
+		untypedReps := make([]*Representation, 0, len(untyped))
+		for _, i := range untyped {
+			untypedReps = append(untypedReps, reps[i])
+		}
+		for j, entity := range f.resolveRepresentations(untypedReps) {
+			resolved[untyped[j]] = entity
+		}
+	}
 
-	// Pull off the key/value for the entity
-	for k, v := range reps {
-		if k == "__typename" {
+	for i, entity := range resolved {
+		if err, failed := entity.(error); failed {
+			failures = append(failures, f.entityError(indexes[i], reps[i].TypeName, err))
 			continue
 		}
-		keyName = k
-		keyValue = v
-		break
+		entities[indexes[i]] = f.entityItem(results, reps[i].TypeName, entity, p)
 	}
 
-	if keyName == "" {
-		return nil, fmt.Errorf("no key field given")
+	if len(failures) > 0 {
+		if results == nil {
+			return nil, failures[0]
+		}
+		sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+		for _, err := range failures {
+			results.fail(p.Info, err)
+		}
 	}
 
-	if f.entityResolver != nil {
-		f.entityResolver(&Representation{
-			TypeName: typeName,
-			KeyName:  keyName,
-			KeyValue: keyValue,
-		})
-	}*/
-	//return fetchEntityByKey(typeName, keyName, keyValue)
+	return entities, nil
+}
 
+// resolveRepresentations resolves representations with the entity resolver
+// or the batch entity resolver, the error of a representation takes the
+// place of its entity
+func (f *Federation) resolveRepresentations(reps []*Representation) []interface{} {
+	resolved := make([]interface{}, len(reps))
+	switch {
+	case f.batchEntityResolver != nil:
+		batch, err := f.batchEntityResolver(reps)
+		if err == nil && len(batch) != len(reps) {
+			err = fmt.Errorf("batch entity resolver returned %d entities for %d representations", len(batch), len(reps))
+		}
+		for i := range reps {
+			if err != nil {
+				resolved[i] = err
+				continue
+			}
+			// the batch resolver can fail single representations by returning an error in their place
+			resolved[i] = batch[i]
+		}
+	case f.entityResolver != nil:
+		for i, rep := range reps {
+			entity, err := f.entityResolver(rep)
+			if err != nil {
+				resolved[i] = err
+				continue
+			}
+			resolved[i] = entity
+		}
+	default:
+		for i := range reps {
+			resolved[i] = ErrNoEntityResolver
+		}
+	}
+	return resolved
 }
 
 // automatically build _Entity union by seaching for entity types
-func (f *Federation) buildEntityType(queryFields, mutationFields graphql.Fields) {
+func (f *Federation) buildEntityType(queryFields, mutationFields graphql.Fields) error {
 
 	f.objects = make(map[string]*graphql.Object)
 	f.interfaces = make(map[string]*graphql.Interface)
 
 	// recurse through entity types to gather possible types
 	for _, v := range queryFields {
-		obj, ok := v.Type.(*graphql.Object)
-		if ok {
-			findTypes(f.objects, f.interfaces, obj, false)
-		}
-
+		f.findFieldTypes(v.Type)
 	}
 
 	for _, v := range mutationFields {
-		obj, ok := v.Type.(*graphql.Object)
-		if ok {
-			findTypes(f.objects, f.interfaces, obj, false)
-		}
+		f.findFieldTypes(v.Type)
+	}
+
+	for _, t := range f.types {
+		f.findFieldTypes(t)
 	}
 
 	//fmt.Fprintln(os.Stdout, "total objects found: ", len(f.objects))
 
-	entityTypes := make([]*graphql.Object, 0, 10)
-	for _, obj := range f.objects {
-		keyFields, err := getKeyDirectives(obj)
+	// interfaces with a @key are entity interfaces, resolved through their implementations
+	f.entityInterfaces = make([]*graphql.Interface, 0)
+	for _, iface := range sortInterfaces(f.interfaces) {
+		keyFields, err := keyDirectives(iface.Extensions())
 		if err != nil {
-			fmt.Fprintln(os.Stdout, "err getting keys: ", err)
+			return fmt.Errorf("%s: %s", iface.Name(), err)
 		}
 		if len(keyFields) > 0 {
-			entityTypes = append(entityTypes, obj)
+			f.entityInterfaces = append(f.entityInterfaces, iface)
 		}
 	}
 
-	if len(entityTypes) == 0 {
-		fmt.Fprintln(os.Stdout, "no entity types found")
+	// entities only referenced through resolvable: false keys stay out of the union
+	entityTypes := make([]*graphql.Object, 0, 10)
+	for _, obj := range sortObjects(f.objects) {
+		keyFields, err := resolvableKeyDirectives(obj.Extensions())
+		if err != nil {
+			return fmt.Errorf("%s: %s", obj.Name(), err)
+		}
+		if len(keyFields) > 0 {
+			entityTypes = append(entityTypes, obj)
+		}
 	}
 
 	f.entityType = graphql.NewUnion(graphql.UnionConfig{
-		Name:  "_Entity",
-		Types: entityTypes,
-		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
-			fmt.Fprintln(os.Stdout, "union resolv called: ", p.Value)
-			return nil
-		},
+		Name:        "_Entity",
+		Types:       entityTypes,
+		ResolveType: f.resolveEntityType,
 	})
+	return nil
+}
 
+// findFieldTypes gathers the object and interface types reachable from a root field type
+func (f *Federation) findFieldTypes(t graphql.Type) {
+	switch t := t.(type) {
+	case *graphql.Object:
+		findTypes(f.objects, f.interfaces, t, false)
+	case *graphql.Interface:
+		f.interfaces[t.Name()] = t
+	case *graphql.List:
+		f.findFieldTypes(t.OfType)
+	case *graphql.NonNull:
+		f.findFieldTypes(t.OfType)
+	}
 }
 
+// BuildSubgraphSchema builds the subgraph schema from the root fields, it
+// returns nil when the build fails and Error reports why
 func (f *Federation) BuildSubgraphSchema(queryFields, mutationFields graphql.Fields) *graphql.Schema {
+	return f.buildSchema(queryFields, mutationFields, nil)
+}
 
-	f.buildEntityType(queryFields, mutationFields)
+// buildSchema builds the subgraph schema, with an optional subscription root type
+func (f *Federation) buildSchema(queryFields, mutationFields graphql.Fields, subscriptionType *graphql.Object) *graphql.Schema {
 
-	queryFields["_entities"] = &graphql.Field{
-		Type: f.entityType,
-		Args: graphql.FieldConfigArgument{
-			"representations": &graphql.ArgumentConfig{
-				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(AnyType))),
+	if err := f.buildEntityType(queryFields, mutationFields); err != nil {
+		f.err = err
+		f.schema = nil
+		return nil
+	}
+
+	// the _entities field is only part of the schema when there are entities to resolve
+	if len(f.entityType.Types()) > 0 {
+		queryFields["_entities"] = &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(f.entityType)),
+			Args: graphql.FieldConfigArgument{
+				"representations": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(AnyType))),
+				},
 			},
-		},
-		Resolve: f.resolveEntity,
+			Resolve: f.resolveEntity,
+		}
 	}
 	queryFields["_service"] = &graphql.Field{
 		Type: graphql.NewNonNull(serviceType),
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			sdl, _ := printSDL(f.schema, f.entityType)
+			sdl, _ := printSDL(f.schema, f.entityType, f.sdlOptions())
 
 			return struct {
 				SDL string `json:"sdl"`
@@ -164,12 +292,15 @@ func (f *Federation) BuildSubgraphSchema(queryFields, mutationFields graphql.Fie
 			Fields: queryFields,
 		},
 	)
-	var mutationType = graphql.NewObject(
-		graphql.ObjectConfig{
-			Name:   "Mutation",
-			Fields: mutationFields,
-		},
-	)
+	var mutationType *graphql.Object
+	if len(mutationFields) > 0 {
+		mutationType = graphql.NewObject(
+			graphql.ObjectConfig{
+				Name:   "Mutation",
+				Fields: mutationFields,
+			},
+		)
+	}
 
 	/*fmt.Fprintln(os.Stdout, "look in query type")
 	for k, v := range queryType.Fields() {
@@ -185,21 +316,105 @@ func (f *Federation) BuildSubgraphSchema(queryFields, mutationFields graphql.Fie
 		fmt.Fprintln(os.Stdout, v.Type)
 	}*/
 
-	schema, _ := graphql.NewSchema(
+	var directives []*graphql.Directive
+	if len(f.directives) > 0 {
+		directives = append(directives, graphql.SpecifiedDirectives...)
+		directives = append(directives, f.directives...)
+	}
+
+	schema, err := graphql.NewSchema(
 		graphql.SchemaConfig{
-			Query:    queryType,
-			Mutation: mutationType,
-			Types: []graphql.Type{
-				graphql.NewInputObject(graphql.InputObjectConfig{}),
-			},
+			Query:        queryType,
+			Mutation:     mutationType,
+			Subscription: subscriptionType,
+			Types:        f.types,
+			Directives:   directives,
 		},
 	)
+	f.err = err
+	if f.err == nil {
+		schema.AddExtensions(tracingExtension{}, entityExtension{})
+	}
 
 	f.schema = &schema
 
+	if f.err == nil {
+		f.err = f.validate()
+	}
+	if f.err == nil && !f.wrappedResolvers && !f.ownTypes && (f.enforceAuthorization || f.hasContextArguments()) {
+		return f.buildWrappedSchema()
+	}
+	if f.err == nil && !f.wrappedResolvers {
+		f.err = f.applyContextArguments()
+	}
+	if f.err == nil && f.enforceAuthorization && !f.wrappedResolvers {
+		f.err = f.applyAuthorization()
+	}
+	if f.err == nil && f.hideInaccessible {
+		f.hideInaccessibleElements()
+	}
+	if f.err != nil {
+		f.schema = nil
+	}
+
 	return f.schema
 }
 
+// buildWrappedSchema builds the schema again from copies of its types. The
+// context and authorization wrappers go on the copies, the types given to
+// BuildSubgraphSchema keep their resolvers and can be built again.
+func (f *Federation) buildWrappedSchema() *graphql.Schema {
+	b := newContractBuilder(f.schema, ContractConfig{})
+	queryFields, mutationFields, subscriptionFields, err := b.build(f.entityType)
+	if err != nil {
+		f.err = err
+		f.schema = nil
+		return nil
+	}
+
+	types := f.types
+	f.types, f.ownTypes = b.copiedTypes(types), true
+	defer func() {
+		f.types, f.ownTypes = types, false
+	}()
+	return f.buildSchema(queryFields, mutationFields, subscriptionObject(subscriptionFields))
+}
+
+// validate checks the federation directives used across the built schema
+func (f *Federation) validate() error {
+	sites, err := collectDirectiveSites(f.schema)
+	if err != nil {
+		return err
+	}
+	if err := validateDirectives(sites); err != nil {
+		return err
+	}
+	if err := validateCustomDirectives(sites, f.directives, f.repeatable); err != nil {
+		return err
+	}
+	if err := f.validateComposedDirectives(); err != nil {
+		return err
+	}
+	if err := f.validateKeyFieldSets(); err != nil {
+		return err
+	}
+	if err := validateContexts(sites); err != nil {
+		return err
+	}
+	if err := validateOverrideLabels(sites); err != nil {
+		return err
+	}
+	if err := f.validateEntityInterfaces(); err != nil {
+		return err
+	}
+	return f.validateTypedResolvers()
+}
+
+// Error returns the error, if any, encountered while building the subgraph schema
+func (f *Federation) Error() error {
+	return f.err
+}
+
 func (f *Federation) Schema() *graphql.Schema {
 	return f.schema
 }
@@ -212,8 +427,52 @@ func (f *Federation) SetEntityBatchResolver(resolverFn EntityResolverBatchFn) {
 	f.batchEntityResolver = resolverFn
 }
 
+// SetExtensionStyle sets how types marked with Extends are printed in the SDL,
+// as "extend type" (the default) or with the @extends directive
+func (f *Federation) SetExtensionStyle(style ExtensionStyle) {
+	f.extensionStyle = style
+}
+
+// AddDirective declares a custom executable or type-system directive in the
+// subgraph schema, call it before BuildSubgraphSchema
+func (f *Federation) AddDirective(directive *graphql.Directive) {
+	f.directives = append(f.directives, directive)
+}
+
+// AddTypes keeps types in the subgraph schema even when they aren't reachable
+// from the root fields, like objects only returned by _entities or through
+// an interface, call it before BuildSubgraphSchema
+func (f *Federation) AddTypes(types ...graphql.Type) {
+	f.types = append(f.types, types...)
+}
+
+// AddRepeatableDirective is like AddDirective for directives that can be
+// applied more than once to the same element
+func (f *Federation) AddRepeatableDirective(directive *graphql.Directive) {
+	f.AddDirective(directive)
+	if f.repeatable == nil {
+		f.repeatable = make(map[string]bool)
+	}
+	f.repeatable[directive.Name] = true
+}
+
+func (f *Federation) sdlOptions() *sdlOptions {
+	return &sdlOptions{
+		extensionStyle: f.extensionStyle,
+		repeatable:     f.repeatable,
+		composed:       f.composed,
+		minVersion:     f.linkVersion,
+		// schemas built from SDL keep the descriptions of their document
+		objectDescriptions: f.fromSDL,
+	}
+}
+
+// PrintSDL prints the subgraph SDL, it is empty when the schema failed to build
 func (f *Federation) PrintSDL() string {
-	sdl, _ := printSDL(f.schema, f.entityType)
+	if f.schema == nil {
+		return ""
+	}
+	sdl, _ := printSDL(f.schema, f.entityType, f.sdlOptions())
 	return sdl
 }
 
@@ -227,7 +486,7 @@ var serviceType = graphql.NewObject(graphql.ObjectConfig{
 })
 
 type Any struct {
-	value []map[string]interface{}
+	value map[string]interface{}
 }
 
 func (id *Any) String() string {
@@ -237,7 +496,7 @@ func (id *Any) String() string {
 
 func NewAny(v string) *Any {
 	a := &Any{}
-	json.Unmarshal([]byte(v), a.value)
+	json.Unmarshal([]byte(v), &a.value)
 	return a
 }
 
@@ -256,55 +515,109 @@ var AnyType = graphql.NewScalar(graphql.ScalarConfig{
 			return nil
 		}
 	},
-	// ParseValue parses GraphQL variables from `string` to `Any`.
+	// ParseValue parses GraphQL variables to a representation map.
 	ParseValue: func(value interface{}) interface{} {
 		switch value := value.(type) {
+		case map[string]interface{}:
+			return value
 		case string:
-			return NewAny(value)
+			return NewAny(value).value
 		case *string:
-			return NewAny(*value)
+			return NewAny(*value).value
 		default:
 			return nil
 		}
 	},
-	// ParseLiteral parses GraphQL AST value to `CustomID`.
+	// ParseLiteral parses GraphQL AST value to a representation map.
 	ParseLiteral: func(valueAST ast.Value) interface{} {
 		switch valueAST := valueAST.(type) {
+		case *ast.ObjectValue:
+			return valueFromAST(valueAST)
 		case *ast.StringValue:
-			return NewAny(valueAST.Value)
+			return NewAny(valueAST.Value).value
 		default:
 			return nil
 		}
 	},
 })
 
+// valueFromAST converts a literal AST value into plain Go values
+func valueFromAST(valueAST ast.Value) interface{} {
+	switch valueAST := valueAST.(type) {
+	case *ast.ObjectValue:
+		obj := make(map[string]interface{}, len(valueAST.Fields))
+		for _, field := range valueAST.Fields {
+			obj[field.Name.Value] = valueFromAST(field.Value)
+		}
+		return obj
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(valueAST.Values))
+		for _, v := range valueAST.Values {
+			list = append(list, valueFromAST(v))
+		}
+		return list
+	case *ast.IntValue:
+		if i, err := strconv.ParseInt(valueAST.Value, 10, 64); err == nil {
+			return i
+		}
+		return valueAST.Value
+	case *ast.FloatValue:
+		if v, err := strconv.ParseFloat(valueAST.Value, 64); err == nil {
+			return v
+		}
+		return valueAST.Value
+	case *ast.BooleanValue:
+		return valueAST.Value
+	case *ast.StringValue:
+		return valueAST.Value
+	case *ast.EnumValue:
+		return valueAST.Value
+	default:
+		return nil
+	}
+}
+
 // return get all key fields for a specific object
 func getKeyDirectives(obj *graphql.Object) ([]string, error) {
+	return keyDirectives(obj.Extensions())
+}
 
-	keyFields := make([]string, 0, 10)
+// keyDirectives returns the fields of every @key directive in an extensions map
+func keyDirectives(extensions map[string]interface{}) ([]string, error) {
 
-	for key, value := range obj.Extensions() {
-		// if extention "name" is directive assert the type
-		if key == "directives" {
-			directives, ok := value.([]*DirectiveValue)
-			if !ok {
-				return nil, fmt.Errorf("key directive has invalid type")
-			}
+	keyFields := make([]string, 0, 10)
 
-			for _, directive := range directives {
-				// skip non-key directives
-				if directive.Name != "key" {
-					continue
-				}
-				for k, v := range directive.Values {
-					if k == "fields" {
-						keyFields = append(keyFields, v.(string))
-					}
-				}
-			}
+	directives, err := getDirectives(extensions)
+	if err != nil {
+		return nil, fmt.Errorf("key directive has invalid type")
+	}
 
+	for _, directive := range findDirectives(directives, KeyDirective) {
+		if fields, ok := directive.Values["fields"].(string); ok {
+			keyFields = append(keyFields, fields)
 		}
+	}
+	return keyFields, nil
+}
+
+// resolvableKeyDirectives returns the fields of the @key directives this
+// subgraph can resolve the entity by, skipping keys with resolvable: false
+func resolvableKeyDirectives(extensions map[string]interface{}) ([]string, error) {
 
+	keyFields := make([]string, 0, 10)
+
+	directives, err := getDirectives(extensions)
+	if err != nil {
+		return nil, fmt.Errorf("key directive has invalid type")
+	}
+
+	for _, directive := range findDirectives(directives, KeyDirective) {
+		if resolvable, ok := directive.Values["resolvable"].(bool); ok && !resolvable {
+			continue
+		}
+		if fields, ok := directive.Values["fields"].(string); ok {
+			keyFields = append(keyFields, fields)
+		}
 	}
 	return keyFields, nil
 }
@@ -46,30 +46,18 @@ func queryTestDatabase(rep *Representation) (interface{}, error) {
 	if rep.TypeName == "User" {
 		for _, v := range testData {
 			obj := reflect.ValueOf(v)
-			field := reflect.Indirect(obj).FieldByName((rep.KeyName))
+			field := reflect.Indirect(obj).FieldByNameFunc(func(name string) bool {
+				return strings.EqualFold(name, rep.KeyName)
+			})
 			// handle field not existing on type
-			if field.IsZero() {
+			if !field.IsValid() || field.IsZero() {
 				continue
 			}
 
-			// switch on keyvalue type
-			switch t := rep.KeyValue.(type) {
-			case string:
-				if field.String() == t {
-					return v, nil
-				}
-			case int64:
-				if field.Int() == t {
-					return v, nil
-				}
-			case float64:
-				if field.Float() == t {
-					return v, nil
-				}
-			default:
-				return nil, fmt.Errorf("cannot determin type of key value: %s", t)
+			// key values are coerced to the key field type, a String here
+			if field.String() == rep.KeyValue {
+				return v, nil
 			}
-
 		}
 		return nil, fmt.Errorf("entity not found in user database: %s=%s", rep.KeyName, rep.KeyValue)
 	}
@@ -312,6 +300,7 @@ func TestResolve(t *testing.T) {
 func TestEntityResolve(t *testing.T) {
 
 	fed := buildSubgraphSchema()
+	fed.SetEntityResolver(queryTestDatabase)
 
 	// Query
 	query := `
@@ -323,8 +312,9 @@ func TestEntityResolve(t *testing.T) {
 		Schema:        *fed.Schema(),
 		RequestString: query,
 		VariableValues: map[string]interface{}{
-			"_representations": []map[string]interface{}{
-				{"__typename": "User", "id": "1"},
+			"_representations": []interface{}{
+				map[string]interface{}{"__typename": "User", "id": "1"},
+				map[string]interface{}{"__typename": "User", "id": "3"},
 			},
 		},
 	}
@@ -334,8 +324,41 @@ func TestEntityResolve(t *testing.T) {
 	}
 
 	rJSON, _ := json.Marshal(r)
-	if string(rJSON) != `{"data":{"user":{"id":"1","name":"Bilbo"}}}` {
+	if string(rJSON) != `{"data":{"_entities":[{"id":"1","name":"Bilbo"},{"id":"3","name":"Gandalf"}]}}` {
+		fmt.Fprintln(os.Stdout, string(rJSON))
 		t.Errorf("invalid query results")
 	}
 
 }
+
+func TestAddTypes(t *testing.T) {
+
+	// Review is only returned by _entities, it isn't reachable from the root fields
+	reviewType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Review",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		},
+		Extensions: mediaKey(),
+	})
+
+	fed := NewFederation()
+	fed.AddTypes(reviewType)
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"version": &graphql.Field{
+			Type: graphql.String,
+		},
+	}, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{`union _Entity = Review`, `type Review @key(fields: "id") {`} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+}
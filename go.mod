@@ -1,5 +1,5 @@
 module github.com/jesse-apollo/gofed
 
-go 1.16
+go 1.18
 
 require github.com/graphql-go/graphql v0.8.0
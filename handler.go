@@ -0,0 +1,285 @@
+package gofed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// DefaultMaxRequestSize is the largest request body, in bytes, Handler reads
+// unless changed with SetMaxRequestSize
+const DefaultMaxRequestSize = 1 << 20
+
+// media types of GraphQL-over-HTTP requests and responses
+const (
+	jsonMediaType            = "application/json"
+	graphqlResponseMediaType = "application/graphql-response+json"
+)
+
+// SetMaxRequestSize sets the largest request body, in bytes, Handler reads,
+// larger requests are rejected with 413. 0 restores DefaultMaxRequestSize.
+func (f *Federation) SetMaxRequestSize(size int64) {
+	f.maxRequestSize = size
+}
+
+// graphqlRequest is the GraphQL-over-HTTP request, sent as the JSON body of a
+// POST or the query parameters of a GET
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+// Handler returns an http.Handler serving the subgraph schema following the
+// GraphQL-over-HTTP spec. It accepts GET requests, for queries only, and POST
+// requests with a JSON body, holding the query, operationName and variables.
+// Responses are application/graphql-response+json when the Accept header
+// allows it, with a 400 status for requests that fail before execution, and
+// application/json otherwise. Once execution started the status is 200 and
+// the response has a data entry, null when an error reached the root fields.
+// Resolvers get the context of the request, operations over the maximum cost
+// set with SetMaxCost are rejected. When the router sends TraceHeader, the
+// response carries a federated trace of the resolver timings and errors in
+// extensions.ftv1.
+func (f *Federation) Handler() http.Handler {
+	return http.HandlerFunc(f.serveHTTP)
+}
+
+func (f *Federation) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	mediaType, ok := responseMediaType(r.Header.Values("Accept"))
+	if !ok {
+		writeHTTPError(w, jsonMediaType, http.StatusNotAcceptable,
+			fmt.Errorf("Accept must allow %s or %s", graphqlResponseMediaType, jsonMediaType))
+		return
+	}
+
+	var req *graphqlRequest
+	var status int
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		req, status, err = readGetRequest(r)
+	case http.MethodPost:
+		req, status, err = f.readPostRequest(r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		status, err = http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+	if err == nil && req.Query == "" {
+		status, err = http.StatusBadRequest, fmt.Errorf("request has no query")
+	}
+	// the document is parsed once, a syntax error is answered like a failed validation
+	var doc *ast.Document
+	var parseErr error
+	if err == nil {
+		doc, parseErr = parser.Parse(parser.ParseParams{Source: req.Query})
+	}
+	if err == nil && parseErr == nil && r.Method == http.MethodGet && operationType(doc, req.OperationName) == ast.OperationTypeMutation {
+		w.Header().Set("Allow", "POST")
+		status, err = http.StatusMethodNotAllowed, fmt.Errorf("mutations must be sent with POST")
+	}
+	if err != nil {
+		writeHTTPError(w, mediaType, status, err)
+		return
+	}
+	if f.schema == nil || f.err != nil {
+		writeHTTPError(w, mediaType, http.StatusInternalServerError, fmt.Errorf("subgraph schema is not available"))
+		return
+	}
+
+	ctx := r.Context()
+	var trace *traceRecorder
+	if r.Header.Get(TraceHeader) == ftv1 {
+		trace = newTraceRecorder()
+		ctx = withTraceRecorder(ctx, trace)
+	}
+	var result *graphql.Result
+	if parseErr != nil {
+		result = &graphql.Result{Errors: gqlerrors.FormatErrors(parseErr)}
+	} else {
+		result = f.execute(ctx, req, doc)
+	}
+	if trace != nil {
+		if result.Extensions == nil {
+			result.Extensions = make(map[string]interface{})
+		}
+		result.Extensions[ftv1] = trace.encode(result)
+	}
+
+	status = http.StatusOK
+	failed := requestFailed(result)
+	if mediaType == graphqlResponseMediaType && failed {
+		status = http.StatusBadRequest
+	}
+	writeResult(w, mediaType, status, result, !failed)
+}
+
+// execute runs the parsed document of a request against the subgraph schema.
+// Documents that don't validate are rejected first, then operations over the
+// maximum cost or that can't be scored, like an unknown operationName.
+func (f *Federation) execute(ctx context.Context, req *graphqlRequest, doc *ast.Document) *graphql.Result {
+	if errs := validateDocument(f.schema, doc); len(errs) > 0 {
+		return &graphql.Result{Errors: errs}
+	}
+	if f.maxCost > 0 {
+		cost, err := f.documentCost(doc, req.OperationName, req.Variables)
+		if err == nil {
+			err = f.checkCost(cost)
+		}
+		if err != nil {
+			return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}}
+		}
+	}
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        *f.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
+
+func readGetRequest(r *http.Request) (*graphqlRequest, int, error) {
+	values := r.URL.Query()
+	req := &graphqlRequest{
+		Query:         values.Get("query"),
+		OperationName: values.Get("operationName"),
+	}
+	if variables := values.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid variables: %s", err)
+		}
+	}
+	if extensions := values.Get("extensions"); extensions != "" {
+		if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid extensions: %s", err)
+		}
+	}
+	return req, 0, nil
+}
+
+func (f *Federation) readPostRequest(r *http.Request) (*graphqlRequest, int, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != jsonMediaType {
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("Content-Type must be %s", jsonMediaType)
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("charset %s is not supported", charset)
+	}
+
+	maxSize := f.maxRequestSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxRequestSize
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("reading request body: %s", err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("request body is larger than %d bytes", maxSize)
+	}
+
+	req := &graphqlRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid request body: %s", err)
+	}
+	return req, 0, nil
+}
+
+// operationType returns the type of the operation named operationName, or ""
+// when the document has no matching operation
+func operationType(doc *ast.Document, operationName string) string {
+	operations := make([]*ast.OperationDefinition, 0, len(doc.Definitions))
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			operations = append(operations, op)
+		}
+	}
+	for _, op := range operations {
+		if operationName == "" && len(operations) == 1 {
+			return op.Operation
+		}
+		if op.Name != nil && op.Name.Value == operationName {
+			return op.Operation
+		}
+	}
+	return ""
+}
+
+// responseMediaType picks the response media type from the Accept headers,
+// application/json when there are none, and reports false when neither
+// supported media type is accepted
+func responseMediaType(accept []string) (string, bool) {
+	if len(accept) == 0 {
+		return jsonMediaType, true
+	}
+	for _, header := range accept {
+		for _, mediaRange := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil || params["q"] == "0" {
+				continue
+			}
+			switch mediaType {
+			case graphqlResponseMediaType, "application/*", "*/*":
+				return graphqlResponseMediaType, true
+			case jsonMediaType:
+				return jsonMediaType, true
+			}
+		}
+	}
+	return "", false
+}
+
+// requestFailed reports if a request failed before execution: it failed to
+// parse, validate, pass the cost check or coerce its variables. Such results
+// have no data and none of their errors has a path, as no field was resolved.
+func requestFailed(result *graphql.Result) bool {
+	if result.Data != nil || len(result.Errors) == 0 {
+		return false
+	}
+	for _, err := range result.Errors {
+		if len(err.Path) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// writeHTTPError writes a request error as a GraphQL response without data
+func writeHTTPError(w http.ResponseWriter, mediaType string, status int, err error) {
+	writeResult(w, mediaType, status, &graphql.Result{
+		Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())},
+	}, false)
+}
+
+// graphqlResponse is a graphql.Result whose data entry is left out when Data
+// is nil, requests failing before execution must not have one while executed
+// requests have one even when it is null
+type graphqlResponse struct {
+	Data       *interface{}               `json:"data,omitempty"`
+	Errors     []gqlerrors.FormattedError `json:"errors,omitempty"`
+	Extensions map[string]interface{}     `json:"extensions,omitempty"`
+}
+
+func writeResult(w http.ResponseWriter, mediaType string, status int, result *graphql.Result, hasData bool) {
+	response := &graphqlResponse{
+		Errors:     result.Errors,
+		Extensions: result.Extensions,
+	}
+	if hasData {
+		response.Data = &result.Data
+	}
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,197 @@
+package gofed
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+type testContextKey struct{}
+
+func buildHandlerFederation() *Federation {
+
+	fed := buildSubgraphSchema()
+	fed.SetEntityResolver(queryTestDatabase)
+	return fed
+}
+
+// serveRequest sends a request to the handler of fed and returns the response
+func serveRequest(fed *Federation, method, target, contentType, accept, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	fed.Handler().ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+
+	fed := buildHandlerFederation()
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	entitiesQuery := `{"query": "query Entities($representations: [_Any!]!) { _entities(representations: $representations) { ... on User { name } } } query Other { __typename }", "operationName": "Entities", "variables": {"representations": [{"__typename": "User", "id": "2"}]}}`
+	getQuery := "/graphql?" + url.Values{
+		"query":     {"query ($id: String) { user(id: $id) { name } }"},
+		"variables": {`{"id": "1"}`},
+	}.Encode()
+	mutationQuery := "/graphql?" + url.Values{"query": {"mutation { addUser }"}}.Encode()
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		accept      string
+		body        string
+		status      int
+		mediaType   string
+		response    string
+	}{
+		{"post with variables and operation name", "POST", "/graphql", "application/json", "", entitiesQuery,
+			200, "application/json", `{"data":{"_entities":[{"name":"Frodo"}]}}`},
+		{"get", "GET", getQuery, "", "application/graphql-response+json", "",
+			200, "application/graphql-response+json", `{"data":{"user":{"name":"Bilbo"}}}`},
+		{"accept any", "POST", "/graphql", "application/json; charset=utf-8", "*/*", `{"query": "{ user(id: \"1\") { name } }"}`,
+			200, "application/graphql-response+json", `{"data":{"user":{"name":"Bilbo"}}}`},
+		{"validation error as graphql-response", "POST", "/graphql", "application/json", "application/graphql-response+json", `{"query": "{ nope }"}`,
+			400, "application/graphql-response+json", `{"errors":[{"message":"Cannot query field \"nope\" on type \"Query\".","locations":[{"line":1,"column":3}]}]}`},
+		{"validation error as json", "POST", "/graphql", "application/json", "application/json", `{"query": "{ nope }"}`,
+			200, "application/json", `{"errors":[{"message":"Cannot query field \"nope\" on type \"Query\".","locations":[{"line":1,"column":3}]}]}`},
+		{"invalid body", "POST", "/graphql", "application/json", "", `{"query": `,
+			400, "application/json", `{"errors":[{"message":"invalid request body: unexpected end of JSON input","locations":[]}]}`},
+		{"missing query", "POST", "/graphql", "application/json", "", `{}`,
+			400, "application/json", `{"errors":[{"message":"request has no query","locations":[]}]}`},
+		{"unsupported content type", "POST", "/graphql", "text/plain", "", `{ user(id: "1") { name } }`,
+			415, "application/json", `{"errors":[{"message":"Content-Type must be application/json","locations":[]}]}`},
+		{"mutation over get", "GET", mutationQuery, "", "", "",
+			405, "application/json", `{"errors":[{"message":"mutations must be sent with POST","locations":[]}]}`},
+		{"unsupported method", "PUT", "/graphql", "application/json", "", `{}`,
+			405, "application/json", `{"errors":[{"message":"method PUT is not allowed","locations":[]}]}`},
+		{"not acceptable", "POST", "/graphql", "application/json", "text/html", `{"query": "{ __typename }"}`,
+			406, "application/json", `{"errors":[{"message":"Accept must allow application/graphql-response+json or application/json","locations":[]}]}`},
+	}
+	for _, test := range tests {
+		w := serveRequest(fed, test.method, test.target, test.contentType, test.accept, test.body)
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, w.Code)
+		}
+		if mediaType := w.Header().Get("Content-Type"); mediaType != test.mediaType+"; charset=utf-8" {
+			t.Errorf("%s: unexpected content type %s", test.name, mediaType)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != test.response {
+			t.Errorf("%s: unexpected response %s", test.name, body)
+		}
+	}
+
+	if w := serveRequest(fed, "PUT", "/graphql", "application/json", "", `{}`); w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("unexpected Allow header %q", w.Header().Get("Allow"))
+	}
+}
+
+func TestHandlerLimits(t *testing.T) {
+
+	fed := buildHandlerFederation()
+	fed.SetMaxRequestSize(32)
+	w := serveRequest(fed, "POST", "/graphql", "application/json", "", `{"query": "{ user(id: \"1\") { name } }"}`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d: %s", w.Code, w.Body)
+	}
+
+	fed = buildHandlerFederation()
+	fed.SetMaxCost(1)
+	w = serveRequest(fed, "POST", "/graphql", "application/json", "application/graphql-response+json", `{"query": "{ users { name } }"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), ErrCostExceeded.Error()) {
+		t.Errorf("expected cost error, got %d: %s", w.Code, w.Body)
+	}
+	// operations that can't be scored are rejected before execution
+	w = serveRequest(fed, "POST", "/graphql", "application/json", "application/graphql-response+json", `{"query": "query A { users { name } }", "operationName": "B"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `unknown operation \"B\"`) {
+		t.Errorf("expected unknown operation error, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestHandlerContext(t *testing.T) {
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"requestID": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Context.Value(testContextKey{}), nil
+			},
+		},
+	}, nil)
+
+	// middleware values reach the resolvers through the request context
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), testContextKey{}, "r-42")
+		fed.Handler().ServeHTTP(w, r.WithContext(ctx))
+	})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/graphql?query={requestID}", nil))
+	if body := strings.TrimSpace(w.Body.String()); body != `{"data":{"requestID":"r-42"}}` {
+		t.Errorf("unexpected response %s", body)
+	}
+}
+
+func TestHandlerExecutionErrors(t *testing.T) {
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"version": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Args: graphql.FieldConfigArgument{
+				"major": &graphql.ArgumentConfig{
+					Type: graphql.Int,
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return nil, errors.New("version unavailable")
+			},
+		},
+	}, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		response string
+	}{
+		// the error reaches the root, data is null but the request was executed
+		{"null data", `{"query": "{ version }"}`,
+			200, `{"data":null,"errors":[{"message":"version unavailable","locations":[{"line":1,"column":3}],"path":["version"]}]}`},
+		{"invalid variables", `{"query": "query ($major: Int) { version(major: $major) }", "variables": {"major": "one"}}`,
+			400, ""},
+		{"fragment cycle", `{"query": "{ ...A } fragment A on Query { ...B } fragment B on Query { ...A }"}`,
+			400, ""},
+	}
+	for _, test := range tests {
+		w := serveRequest(fed, "POST", "/graphql", "application/json", "application/graphql-response+json", test.body)
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d: %s", test.name, test.status, w.Code, w.Body)
+		}
+		body := strings.TrimSpace(w.Body.String())
+		if test.response != "" && body != test.response {
+			t.Errorf("%s: unexpected response %s", test.name, body)
+		}
+		if test.status == 400 && strings.Contains(body, `"data"`) {
+			t.Errorf("%s: request error has a data entry: %s", test.name, body)
+		}
+	}
+}
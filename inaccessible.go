@@ -0,0 +1,256 @@
+package gofed
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// SetHideInaccessible hides the types, fields, arguments, enum values and
+// input fields marked @inaccessible from introspection (__schema and __type),
+// they are still printed in the _service SDL. It must be called before
+// BuildSubgraphSchema.
+func (f *Federation) SetHideInaccessible(hide bool) {
+	f.hideInaccessible = hide
+}
+
+// hideInaccessibleElements collects every @inaccessible element of the schema
+// and adds the extension filtering them out of its introspection results
+func (f *Federation) hideInaccessibleElements() {
+	hidden := make(map[interface{}]bool)
+	hide := func(element interface{}, extensions map[string]interface{}) {
+		directives, _ := getDirectives(extensions)
+		if hasDirective(directives, InaccessibleDirective) {
+			hidden[element] = true
+		}
+	}
+	hideFields := func(fields graphql.FieldDefinitionMap) {
+		for _, field := range fields {
+			hide(field, field.Extensions)
+			for _, arg := range field.Args {
+				hide(arg, arg.Extensions)
+			}
+		}
+	}
+
+	for _, t := range f.schema.TypeMap() {
+		if strings.HasPrefix(t.Name(), "__") {
+			continue
+		}
+		switch t := t.(type) {
+		case *graphql.Object:
+			hide(t, t.Extensions())
+			hideFields(t.Fields())
+		case *graphql.Interface:
+			hide(t, t.Extensions())
+			hideFields(t.Fields())
+		case *graphql.Union:
+			hide(t, t.Extensions())
+		case *graphql.Enum:
+			hide(t, t.Extensions())
+			for _, v := range t.Values() {
+				hide(v, v.Extensions)
+			}
+		case *graphql.InputObject:
+			hide(t, t.Extensions())
+			for _, field := range t.Fields() {
+				hide(field, field.Extensions)
+			}
+		case *graphql.Scalar:
+			hide(t, t.Extensions())
+		}
+	}
+
+	if len(hidden) > 0 {
+		f.schema.AddExtensions(&inaccessibleExtension{hidden: hidden})
+	}
+}
+
+type hiddenResultsKey struct{}
+
+// hiddenResults collects, while a request executes, where hidden elements
+// ended up in the introspection results
+type hiddenResults struct {
+	mu      sync.Mutex
+	results []hiddenResult
+}
+
+// hiddenResult is the response path of an introspection result holding
+// hidden elements, indices lists the hidden items of a list, a nil indices
+// hides the whole value
+type hiddenResult struct {
+	path    []interface{}
+	indices []int
+}
+
+func (r *hiddenResults) add(path []interface{}, indices []int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.results = append(r.results, hiddenResult{path: path, indices: indices})
+}
+
+// apply removes the hidden elements from the response data, the deepest
+// paths first so the list indices of their parents still hold
+func (r *hiddenResults) apply(result *graphql.Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sort.SliceStable(r.results, func(i, j int) bool {
+		return len(r.results[i].path) > len(r.results[j].path)
+	})
+	for _, hidden := range r.results {
+		parent := result.Data
+		for _, key := range hidden.path[:len(hidden.path)-1] {
+			parent = responseValue(parent, key)
+		}
+		key := hidden.path[len(hidden.path)-1]
+		if hidden.indices == nil {
+			setResponseValue(parent, key, nil)
+			continue
+		}
+		list, ok := responseValue(parent, key).([]interface{})
+		if !ok {
+			continue
+		}
+		kept := make([]interface{}, 0, len(list))
+		for i, item := range list {
+			if !containsInt(hidden.indices, i) {
+				kept = append(kept, item)
+			}
+		}
+		setResponseValue(parent, key, kept)
+	}
+}
+
+// responseValue returns the value of a map key or list index in response data
+func responseValue(data interface{}, key interface{}) interface{} {
+	switch data := data.(type) {
+	case map[string]interface{}:
+		if key, ok := key.(string); ok {
+			return data[key]
+		}
+	case []interface{}:
+		if i, ok := key.(int); ok && i < len(data) {
+			return data[i]
+		}
+	}
+	return nil
+}
+
+func setResponseValue(data interface{}, key interface{}, value interface{}) {
+	switch data := data.(type) {
+	case map[string]interface{}:
+		if key, ok := key.(string); ok {
+			data[key] = value
+		}
+	case []interface{}:
+		if i, ok := key.(int); ok && i < len(data) {
+			data[i] = value
+		}
+	}
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// inaccessibleExtension filters hidden elements out of the introspection
+// results of a single schema. The introspection resolvers are shared by every
+// graphql-go schema, so their results are filtered once execution is done
+// rather than changing the resolvers.
+type inaccessibleExtension struct {
+	hidden map[interface{}]bool
+}
+
+var _ graphql.Extension = (*inaccessibleExtension)(nil)
+
+func (e *inaccessibleExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (e *inaccessibleExtension) Name() string {
+	return "inaccessible"
+}
+
+func (e *inaccessibleExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (e *inaccessibleExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (e *inaccessibleExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	// graphql.Do leaves the context nil when Params has none
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results := &hiddenResults{}
+	return context.WithValue(ctx, hiddenResultsKey{}, results), results.apply
+}
+
+func (e *inaccessibleExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	results, _ := ctx.Value(hiddenResultsKey{}).(*hiddenResults)
+	introspection := info.FieldName == "__type" || info.ParentType != nil && strings.HasPrefix(info.ParentType.Name(), "__")
+	if results == nil || !introspection {
+		return ctx, func(interface{}, error) {}
+	}
+	path := info.Path.AsArray()
+	return ctx, func(value interface{}, err error) {
+		if err != nil || value == nil {
+			return
+		}
+		if info.FieldName == "__type" {
+			if e.isHidden(value) {
+				results.add(path, nil)
+			}
+			return
+		}
+		if indices := e.hiddenIndices(value); len(indices) > 0 {
+			results.add(path, indices)
+		}
+	}
+}
+
+func (e *inaccessibleExtension) HasResult() bool {
+	return false
+}
+
+func (e *inaccessibleExtension) GetResult(context.Context) interface{} {
+	return nil
+}
+
+func (e *inaccessibleExtension) isHidden(element interface{}) bool {
+	v := reflect.ValueOf(element)
+	if v.Kind() != reflect.Ptr {
+		return false
+	}
+	return e.hidden[element]
+}
+
+// hiddenIndices returns the indices of the hidden elements of a list
+// returned by an introspection resolver, like __Type.fields
+func (e *inaccessibleExtension) hiddenIndices(value interface{}) []int {
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice {
+		return nil
+	}
+	var indices []int
+	for i := 0; i < list.Len(); i++ {
+		if e.isHidden(list.Index(i).Interface()) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
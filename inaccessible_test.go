@@ -0,0 +1,181 @@
+package gofed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildInaccessibleFederation(hide bool) *Federation {
+
+	var colorEnum = graphql.NewEnum(graphql.EnumConfig{
+		Name: "Color",
+		Values: graphql.EnumValueConfigMap{
+			"RED": &graphql.EnumValueConfig{
+				Value: "red",
+			},
+			"SECRET": &graphql.EnumValueConfig{
+				Value:      "secret",
+				Extensions: Directives(Inaccessible()),
+			},
+		},
+	})
+
+	var internalType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Internal",
+			Fields: graphql.Fields{
+				"note": &graphql.Field{
+					Type: graphql.String,
+				},
+			},
+			Extensions: Directives(Inaccessible()),
+		},
+	)
+
+	var productType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Product",
+			Fields: graphql.Fields{
+				"upc": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"price": &graphql.Field{
+					Type: graphql.Int,
+					Args: graphql.FieldConfigArgument{
+						"currency": &graphql.ArgumentConfig{
+							Type: graphql.String,
+						},
+						"discount": &graphql.ArgumentConfig{
+							Type:       graphql.Int,
+							Extensions: Directives(Inaccessible()),
+						},
+					},
+				},
+				"secret": &graphql.Field{
+					Type:       graphql.String,
+					Extensions: Directives(Inaccessible()),
+				},
+				"internal": &graphql.Field{
+					Type:       internalType,
+					Extensions: Directives(Inaccessible()),
+				},
+				"color": &graphql.Field{
+					Type: colorEnum,
+				},
+			},
+			Extensions: upcKey(),
+		},
+	)
+
+	fed := NewFederation()
+	fed.SetHideInaccessible(hide)
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"product": &graphql.Field{
+			Type: productType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"upc": "1", "secret": "shh"}, nil
+			},
+		},
+	}, nil)
+
+	return fed
+}
+
+func TestHideInaccessible(t *testing.T) {
+
+	hidden := buildInaccessibleFederation(true)
+	visible := buildInaccessibleFederation(false)
+	if err := hidden.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	run := func(fed *Federation, query string) string {
+		r := graphql.Do(graphql.Params{
+			Schema:        *fed.Schema(),
+			RequestString: query,
+		})
+		if len(r.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", r.Errors)
+		}
+		rJSON, _ := json.Marshal(r.Data)
+		return string(rJSON)
+	}
+
+	tests := []struct {
+		query   string
+		hidden  string
+		visible string
+	}{
+		{
+			`{ __type(name: "Product") { fields { name } } }`,
+			`{"__type":{"fields":[{"name":"color"},{"name":"price"},{"name":"upc"}]}}`,
+			`{"__type":{"fields":[{"name":"color"},{"name":"internal"},{"name":"price"},{"name":"secret"},{"name":"upc"}]}}`,
+		},
+		{
+			`{ __type(name: "Internal") { name } }`,
+			`{"__type":null}`,
+			`{"__type":{"name":"Internal"}}`,
+		},
+		{
+			`{ __type(name: "Color") { enumValues { name } } }`,
+			`{"__type":{"enumValues":[{"name":"RED"}]}}`,
+			"",
+		},
+		{
+			`{ __type(name: "Product") { fields { args { name } } } }`,
+			`{"__type":{"fields":[{"args":[]},{"args":[{"name":"currency"}]},{"args":[]}]}}`,
+			"",
+		},
+	}
+	for _, test := range tests {
+		if result := run(hidden, test.query); result != test.hidden {
+			t.Errorf("%s: unexpected hidden result %s", test.query, result)
+		}
+		if result := run(visible, test.query); test.visible != "" && result != test.visible {
+			t.Errorf("%s: unexpected visible result %s", test.query, result)
+		}
+	}
+
+	// enum values are listed in no particular order
+	if values := run(visible, `{ __type(name: "Color") { enumValues { name } } }`); !strings.Contains(values, "SECRET") {
+		t.Errorf("enum value missing when not hidden: %s", values)
+	}
+
+	if types := run(hidden, `{ __schema { types { name } } }`); strings.Contains(types, "Internal") {
+		t.Errorf("inaccessible type listed in __schema: %s", types)
+	}
+	if types := run(hidden, `{ s: __schema { all: types { name fields { name } } } }`); strings.Contains(types, "Internal") || strings.Contains(types, `"secret"`) {
+		t.Errorf("inaccessible element listed in aliased __schema: %s", types)
+	}
+
+	// other schemas of the process are left alone
+	plain, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"secret": &graphql.Field{
+					Type:       graphql.String,
+					Extensions: Directives(Inaccessible()),
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected schema error: %s", err)
+	}
+	r := graphql.Do(graphql.Params{Schema: plain, RequestString: `{ __type(name: "Query") { fields { name } } }`})
+	if data, _ := json.Marshal(r.Data); string(data) != `{"__type":{"fields":[{"name":"secret"}]}}` {
+		t.Errorf("unexpected result for a schema not built by gofed: %s", data)
+	}
+
+	// hidden elements can still be queried, and are part of the SDL
+	if result := run(hidden, `{ product { secret } }`); result != `{"product":{"secret":"shh"}}` {
+		t.Errorf("unexpected result %s", result)
+	}
+	if sdl := hidden.PrintSDL(); !strings.Contains(sdl, "  secret: String @inaccessible\n") {
+		t.Errorf("inaccessible field missing from sdl:\n%s", sdl)
+	}
+}
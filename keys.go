@@ -0,0 +1,124 @@
+package gofed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// parseFieldSet parses a field set like "id org { id }" into its selections
+func parseFieldSet(fields string) (*ast.SelectionSet, error) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source:  "{" + fields + "}",
+		Options: parser.ParseOptions{NoLocation: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid field set %q", fields)
+	}
+	if len(doc.Definitions) != 1 {
+		return nil, fmt.Errorf("invalid field set %q", fields)
+	}
+	operation, ok := doc.Definitions[0].(*ast.OperationDefinition)
+	if !ok || operation.SelectionSet == nil || len(operation.SelectionSet.Selections) == 0 {
+		return nil, fmt.Errorf("invalid field set %q", fields)
+	}
+	return operation.SelectionSet, nil
+}
+
+// validateKeyFieldSets checks the field set of every @key against the
+// fields of the object or interface declaring it
+func (f *Federation) validateKeyFieldSets() error {
+	for _, t := range sortTypeMap(f.schema.TypeMap()) {
+		if strings.HasPrefix(t.Name(), "__") {
+			continue
+		}
+		var extensions map[string]interface{}
+		switch t := t.(type) {
+		case *graphql.Object:
+			extensions = t.Extensions()
+		case *graphql.Interface:
+			extensions = t.Extensions()
+		default:
+			continue
+		}
+
+		keys, err := keyDirectives(extensions)
+		if err != nil {
+			return fmt.Errorf("%s: %s", t.Name(), err)
+		}
+		for _, key := range keys {
+			if err := validateKeyFieldSet(t, key); err != nil {
+				return fmt.Errorf("%s: @key(fields: %q): %s", t.Name(), key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateKeyFieldSet walks a key field set against the fields of a type
+func validateKeyFieldSet(t graphql.Type, fields string) error {
+	set, err := parseFieldSet(fields)
+	if err != nil {
+		return err
+	}
+	return validateKeySelections(t, t.Name(), set)
+}
+
+func validateKeySelections(t graphql.Type, path string, set *ast.SelectionSet) error {
+	var definitions graphql.FieldDefinitionMap
+	switch t := t.(type) {
+	case *graphql.Object:
+		definitions = t.Fields()
+	case *graphql.Interface:
+		definitions = t.Fields()
+	}
+
+	for _, selection := range set.Selections {
+		field, ok := selection.(*ast.Field)
+		if !ok {
+			return fmt.Errorf("%s: fragments are not allowed in key field sets", path)
+		}
+		name := field.Name.Value
+		fieldPath := path + "." + name
+		if field.Alias != nil {
+			return fmt.Errorf("%s: aliases are not allowed in key field sets", fieldPath)
+		}
+		if len(field.Directives) > 0 {
+			return fmt.Errorf("%s: directives are not allowed in key field sets", fieldPath)
+		}
+
+		definition, ok := definitions[name]
+		if !ok {
+			return fmt.Errorf("%s: field is not defined on %s", fieldPath, t.Name())
+		}
+		if len(definition.Args) > 0 {
+			return fmt.Errorf("%s: fields with arguments cannot be part of a key", fieldPath)
+		}
+
+		fieldType := definition.Type
+		if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+			fieldType = nonNull.OfType
+		}
+		switch fieldType := fieldType.(type) {
+		case *graphql.List:
+			return fmt.Errorf("%s: list fields cannot be part of a key", fieldPath)
+		case *graphql.Interface, *graphql.Union:
+			return fmt.Errorf("%s: %s fields cannot be part of a key", fieldPath, fieldType.Name())
+		case *graphql.Object:
+			if field.SelectionSet == nil || len(field.SelectionSet.Selections) == 0 {
+				return fmt.Errorf("%s: object fields must select subfields", fieldPath)
+			}
+			if err := validateKeySelections(fieldType, fieldPath, field.SelectionSet); err != nil {
+				return err
+			}
+		default:
+			if field.SelectionSet != nil && len(field.SelectionSet.Selections) > 0 {
+				return fmt.Errorf("%s: %s fields cannot select subfields", fieldPath, fieldType.Name())
+			}
+		}
+	}
+	return nil
+}
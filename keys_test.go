@@ -0,0 +1,98 @@
+package gofed
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildKeyFederation(key string) *Federation {
+
+	var orgType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Org",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+			},
+		},
+	)
+
+	nodeInterface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.ID,
+			},
+		},
+	})
+
+	var userType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "User",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+				"org": &graphql.Field{
+					Type: orgType,
+				},
+				"tags": &graphql.Field{
+					Type: graphql.NewList(graphql.String),
+				},
+				"node": &graphql.Field{
+					Type: nodeInterface,
+				},
+				"avatar": &graphql.Field{
+					Type: graphql.String,
+					Args: graphql.FieldConfigArgument{
+						"size": &graphql.ArgumentConfig{
+							Type: graphql.Int,
+						},
+					},
+				},
+			},
+			Extensions: Directives(&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": key}}),
+		},
+	)
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"me": &graphql.Field{
+			Type: userType,
+		},
+	}, nil)
+
+	return fed
+}
+
+func TestKeyFieldSetValidation(t *testing.T) {
+
+	tests := []struct {
+		key string
+		err string
+	}{
+		{"id", ""},
+		{"id org { id }", ""},
+		{"idd", `User: @key(fields: "idd"): User.idd: field is not defined on User`},
+		{"org { idd }", `User: @key(fields: "org { idd }"): User.org.idd: field is not defined on Org`},
+		{"org", `User: @key(fields: "org"): User.org: object fields must select subfields`},
+		{"id { id }", `User: @key(fields: "id { id }"): User.id: ID fields cannot select subfields`},
+		{"tags", `User: @key(fields: "tags"): User.tags: list fields cannot be part of a key`},
+		{"node { id }", `User: @key(fields: "node { id }"): User.node: Node fields cannot be part of a key`},
+		{"avatar", `User: @key(fields: "avatar"): User.avatar: fields with arguments cannot be part of a key`},
+		{"userId: id", `User: @key(fields: "userId: id"): User.id: aliases are not allowed in key field sets`},
+		{"id {", `User: @key(fields: "id {"): invalid field set "id {"`},
+		{"", `User: @key(fields: ""): invalid field set ""`},
+	}
+	for _, test := range tests {
+		err := buildKeyFederation(test.key).Error()
+		if test.err == "" && err != nil {
+			t.Errorf("%q: unexpected error: %s", test.key, err)
+		}
+		if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%q: expected error %q, got %v", test.key, test.err, err)
+		}
+	}
+}
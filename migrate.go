@@ -0,0 +1,199 @@
+package gofed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// the federation version migrated schemas link to at least
+const migrationVersion = "2.0"
+
+// MigrateSDL converts a Federation 1 subgraph SDL document, like the one
+// printed by PrintSDL for schemas without federation 2 directives, into the
+// equivalent Federation 2 SDL. Along with the SDL it returns notes about
+// anything that needs a manual review.
+func MigrateSDL(sdl string) (string, []string, error) {
+	doc, err := parseSDL(sdl, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	fed := doc.newFederation()
+	if err := fed.Error(); err != nil {
+		return "", nil, err
+	}
+	return fed.MigrateSDL()
+}
+
+// MigrateSDL prints the subgraph schema as a Federation 2 SDL. Extensions
+// become plain types, key fields of extensions lose @external and value
+// types, which any federation 1 subgraph could resolve, are marked
+// @shareable. Along with the SDL it returns notes about anything that needs
+// a manual review.
+func (f *Federation) MigrateSDL() (string, []string, error) {
+	if f.err != nil {
+		return "", nil, f.err
+	}
+	if f.schema == nil {
+		return "", nil, fmt.Errorf("BuildSubgraphSchema must be called first")
+	}
+
+	opts := f.sdlOptions()
+	opts.migrate = true
+	opts.objectDescriptions = true
+	sdl, err := printSDL(f.schema, f.entityType, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	notes, err := f.migrationNotes()
+	if err != nil {
+		return "", nil, err
+	}
+	return sdl, notes, nil
+}
+
+// isValueType reports if an object printed by printFieldsType is a value
+// type, shared by every federation 1 subgraph defining it. The root types
+// aren't value types.
+func isValueType(kind, name string, directives []*DirectiveValue, rootTypes map[string]bool) bool {
+	return kind == "type" && !strings.HasPrefix(name, "_") && !rootTypes[name] && !hasDirective(directives, KeyDirective)
+}
+
+// rootTypeNames returns the names of the root operation types of a schema
+func rootTypeNames(schema *graphql.Schema) map[string]bool {
+	names := make(map[string]bool, 3)
+	for _, root := range []*graphql.Object{schema.QueryType(), schema.MutationType(), schema.SubscriptionType()} {
+		if root != nil {
+			names[root.Name()] = true
+		}
+	}
+	return names
+}
+
+// migrateTypeDirectives returns the federation 2 directives of an object or interface
+func migrateTypeDirectives(kind, name string, directives []*DirectiveValue, rootTypes map[string]bool) []*DirectiveValue {
+	migrated := withoutDirective(directives, ExtendsDirective)
+	if isValueType(kind, name, directives, rootTypes) && !hasDirective(directives, ShareableDirective) {
+		migrated = append(migrated, Shareable())
+	}
+	return migrated
+}
+
+// migrateFieldDirectives returns the federation 2 directives of a field,
+// federation 2 doesn't need the key fields of an extension to be @external
+func migrateFieldDirectives(typeDirectives []*DirectiveValue, field string, directives []*DirectiveValue) []*DirectiveValue {
+	if !hasDirective(typeDirectives, ExtendsDirective) {
+		return directives
+	}
+	for _, key := range findDirectives(typeDirectives, KeyDirective) {
+		fields, _ := key.Values["fields"].(string)
+		if containsString(topLevelKeyFields(fields), field) {
+			return withoutDirective(directives, ExternalDirective)
+		}
+	}
+	return directives
+}
+
+// migrateSites returns the directive sites of the schema as printed by migrateTypeDirectives
+// and migrateFieldDirectives, used to work out the federation version and imports
+func migrateSites(schema *graphql.Schema, entityType *graphql.Union, sites []*directiveSite) []*directiveSite {
+	migrated := make([]*directiveSite, 0, len(sites))
+	typeDirectives := make(map[string][]*DirectiveValue)
+	rootTypes := rootTypeNames(schema)
+
+	for _, site := range sites {
+		directives := site.directives
+		switch site.location {
+		case graphql.DirectiveLocationObject, graphql.DirectiveLocationInterface:
+			typeDirectives[site.path] = directives
+			if !isSkippedType(schema, entityType, site.path) {
+				kind := "type"
+				if site.location == graphql.DirectiveLocationInterface {
+					kind = "interface"
+				}
+				directives = migrateTypeDirectives(kind, site.path, directives, rootTypes)
+			}
+		case graphql.DirectiveLocationFieldDefinition:
+			parts := strings.SplitN(site.path, ".", 2)
+			directives = migrateFieldDirectives(typeDirectives[parts[0]], parts[1], directives)
+		}
+		migrated = append(migrated, &directiveSite{location: site.location, path: site.path, directives: directives})
+	}
+
+	// value types without any directive still become @shareable
+	for _, t := range sortTypeMap(schema.TypeMap()) {
+		if _, ok := t.(*graphql.Object); !ok || isSkippedType(schema, entityType, t.Name()) {
+			continue
+		}
+		if _, ok := typeDirectives[t.Name()]; ok {
+			continue
+		}
+		if directives := migrateTypeDirectives("type", t.Name(), nil, rootTypes); len(directives) > 0 {
+			migrated = append(migrated, &directiveSite{location: graphql.DirectiveLocationObject, path: t.Name(), directives: directives})
+		}
+	}
+
+	return migrated
+}
+
+// migrationNotes lists what the migration changed or couldn't handle and
+// needs a manual review
+func (f *Federation) migrationNotes() ([]string, error) {
+	sites, err := collectDirectiveSites(f.schema)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]string, 0)
+	if version, _ := federationVersion(sites); version != federationV1 {
+		notes = append(notes, fmt.Sprintf("schema already uses federation %s directives", version))
+	}
+
+	rootTypes := rootTypeNames(f.schema)
+	for _, t := range sortTypeMap(f.schema.TypeMap()) {
+		obj, ok := t.(*graphql.Object)
+		if !ok || isSkippedType(f.schema, f.entityType, obj.Name()) {
+			continue
+		}
+		directives, _ := getDirectives(obj.Extensions())
+		if isValueType("type", obj.Name(), directives, rootTypes) && !hasDirective(directives, ShareableDirective) {
+			notes = append(notes, fmt.Sprintf("%s: marked @shareable as a value type, remove it if no other subgraph resolves %s", obj.Name(), obj.Name()))
+		}
+
+		for _, field := range sortFields(obj.Fields()) {
+			path := obj.Name() + "." + field.Name
+			fieldDirectives, _ := getDirectives(field.Extensions)
+			if hasDirective(fieldDirectives, ProvidesDirective) {
+				notes = append(notes, fmt.Sprintf("%s: fields provided with @provides must be @shareable in the subgraphs resolving them", path))
+			}
+			for _, requires := range findDirectives(fieldDirectives, RequiresDirective) {
+				fields, _ := requires.Values["fields"].(string)
+				for _, name := range topLevelKeyFields(fields) {
+					required, ok := obj.Fields()[name]
+					if !ok {
+						notes = append(notes, fmt.Sprintf("%s: @requires field %s is not defined", path, name))
+						continue
+					}
+					if requiredDirectives, _ := getDirectives(required.Extensions); !hasDirective(requiredDirectives, ExternalDirective) {
+						notes = append(notes, fmt.Sprintf("%s: @requires field %s must be @external", path, name))
+					}
+				}
+			}
+		}
+	}
+
+	for _, directive := range f.directives {
+		composed := false
+		for _, c := range f.composed {
+			if c.name == directive.Name {
+				composed = true
+			}
+		}
+		if !composed {
+			notes = append(notes, fmt.Sprintf("@%s: custom directives are left out of the supergraph unless composed with ComposeDirective", directive.Name))
+		}
+	}
+
+	return notes, nil
+}
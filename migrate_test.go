@@ -0,0 +1,108 @@
+package gofed
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestMigrateSDL(t *testing.T) {
+
+	fed1, err := os.ReadFile("testdata/fed1_reviews.graphql")
+	if err != nil {
+		t.Fatalf("error reading schema: %s", err)
+	}
+
+	sdl, notes, err := MigrateSDL(string(fed1))
+	if err != nil {
+		t.Fatalf("unexpected migration error: %s", err)
+	}
+
+	expected := []string{
+		"extend schema\n  @link(url: \"https://specs.apollo.dev/federation/v2.0\", import: [\"@external\", \"@key\", \"@provides\", \"@requires\", \"@shareable\"])\n",
+		"type Rating @shareable {\n",
+		"\" A review of a product\"\ntype Review @key(fields: \"id\") {\n",
+		"type User @key(fields: \"id\") {\n  id: ID!\n  name: String @external\n",
+		"  score: Int @requires(fields: \"name\")\n",
+		"  topReviews(first: Int = 5): [Review]\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(sdl, e) {
+			t.Errorf("migrated sdl is missing %q:\n%s", e, sdl)
+		}
+	}
+	for _, removed := range []string{"extend type", "@extends", "type Review @key(fields: \"id\") @shareable"} {
+		if strings.Contains(sdl, removed) {
+			t.Errorf("migrated sdl should not contain %q:\n%s", removed, sdl)
+		}
+	}
+
+	expectedNotes := []string{
+		"Rating: marked @shareable as a value type, remove it if no other subgraph resolves Rating",
+		"Review.author: fields provided with @provides must be @shareable in the subgraphs resolving them",
+	}
+	if strings.Join(notes, "\n") != strings.Join(expectedNotes, "\n") {
+		t.Errorf("unexpected notes %q", notes)
+	}
+}
+
+func TestMigrateFederation(t *testing.T) {
+
+	fed := buildExtensionFederation(ExtendTypeStyle)
+	sdl, _, err := fed.MigrateSDL()
+	if err != nil {
+		t.Fatalf("unexpected migration error: %s", err)
+	}
+	if !strings.Contains(sdl, "type User @key(fields: \"id\") {\n  id: ID!\n  reviews: [String]\n}") {
+		t.Errorf("extension not migrated:\n%s", sdl)
+	}
+
+	// the subgraph schema itself is left untouched
+	if !strings.Contains(fed.PrintSDL(), "extend type User") {
+		t.Errorf("migration changed the subgraph sdl")
+	}
+
+	if _, _, err := MigrateSDL("type Query { me: Unknown }"); err == nil {
+		t.Errorf("expected an error migrating an invalid schema")
+	}
+}
+
+func TestMigrateRootTypes(t *testing.T) {
+
+	ratingType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Rating",
+		Fields: graphql.Fields{
+			"score": &graphql.Field{Type: graphql.Int},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: graphql.Fields{"rating": &graphql.Field{Type: ratingType}},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Subscription",
+			Fields: graphql.Fields{"ratingAdded": &graphql.Field{Type: ratingType}},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected schema error: %s", err)
+	}
+
+	fed := NewFederation()
+	fed.WrapSchema(&schema, nil)
+	sdl, notes, err := fed.MigrateSDL()
+	if err != nil {
+		t.Fatalf("unexpected migration error: %s", err)
+	}
+
+	// the root types aren't value types
+	if !strings.Contains(sdl, "type Subscription {\n") || !strings.Contains(sdl, "type Rating @shareable {\n") {
+		t.Errorf("unexpected migrated sdl:\n%s", sdl)
+	}
+	if strings.Join(notes, "\n") != "Rating: marked @shareable as a value type, remove it if no other subgraph resolves Rating" {
+		t.Errorf("unexpected notes %q", notes)
+	}
+}
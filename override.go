@@ -0,0 +1,125 @@
+package gofed
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+var (
+	// custom override labels are resolved by the router, e.g. from a coprocessor
+	overrideLabelPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_\-:.]*$`)
+	// percent labels route a share of requests, with up to 8 decimal places
+	overridePercentPattern = regexp.MustCompile(`^percent\((\d{1,3}(?:\.\d{1,8})?)\)$`)
+)
+
+// OverrideRolloutFn reports if an @override label is active for the request
+type OverrideRolloutFn func(ctx context.Context, label string) bool
+
+// SetOverrideRollout replaces how OverrideActive decides if a label is active,
+// by default the labels set with WithOverrideLabels are active
+func (f *Federation) SetOverrideRollout(rolloutFn OverrideRolloutFn) {
+	f.overrideRollout = rolloutFn
+}
+
+type overrideLabelsKey struct{}
+
+// WithOverrideLabels returns a copy of ctx marking the given override labels as
+// active for the request, e.g. from a header set by the router
+func WithOverrideLabels(ctx context.Context, labels ...string) context.Context {
+	return context.WithValue(ctx, overrideLabelsKey{}, labels)
+}
+
+// OverrideLabelsFromContext returns the labels set with WithOverrideLabels
+func OverrideLabelsFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	labels, _ := ctx.Value(overrideLabelsKey{}).([]string)
+	return labels
+}
+
+// OverrideActive is meant to be called by field resolvers. It returns the
+// @override label of the field being resolved and if the label is active for
+// the current request, so both the old and new implementation of a field can
+// log or compare their results during a migration.
+func (f *Federation) OverrideActive(p graphql.ResolveParams) (string, bool) {
+	label := overrideLabel(p.Info)
+	if label == "" {
+		return "", false
+	}
+	if f.overrideRollout != nil {
+		return label, f.overrideRollout(p.Context, label)
+	}
+	return label, containsString(OverrideLabelsFromContext(p.Context), label)
+}
+
+// overrideLabel returns the @override label of the field being resolved
+func overrideLabel(info graphql.ResolveInfo) string {
+	var fields graphql.FieldDefinitionMap
+	switch t := info.ParentType.(type) {
+	case *graphql.Object:
+		fields = t.Fields()
+	case *graphql.Interface:
+		fields = t.Fields()
+	default:
+		return ""
+	}
+	field, ok := fields[info.FieldName]
+	if !ok {
+		return ""
+	}
+	directives, err := getDirectives(field.Extensions)
+	if err != nil {
+		return ""
+	}
+	for _, d := range findDirectives(directives, OverrideDirective) {
+		if label, ok := d.Values["label"].(string); ok {
+			return label
+		}
+	}
+	return ""
+}
+
+// validateOverrideLabel checks label is either percent(N), N between 0 and
+// 100, or a custom label
+func validateOverrideLabel(label string) error {
+	if strings.HasPrefix(label, "percent(") {
+		match := overridePercentPattern.FindStringSubmatch(label)
+		if match == nil {
+			return fmt.Errorf("invalid percent label %q", label)
+		}
+		if percent, _ := strconv.ParseFloat(match[1], 64); percent > 100 {
+			return fmt.Errorf("percent label %q must be between 0 and 100", label)
+		}
+		return nil
+	}
+	if !overrideLabelPattern.MatchString(label) {
+		return fmt.Errorf("invalid label %q", label)
+	}
+	return nil
+}
+
+// validateOverrideLabels checks the label of every @override in the schema
+func validateOverrideLabels(sites []*directiveSite) error {
+	for _, site := range sites {
+		for _, d := range findDirectives(site.directives, OverrideDirective) {
+			value, ok := d.Values["label"]
+			if !ok {
+				continue
+			}
+			label, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("%s: @%s argument \"label\" must be a String", site.path, d.Name)
+			}
+			if err := validateOverrideLabel(label); err != nil {
+				return fmt.Errorf("%s: @%s %s", site.path, d.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package gofed
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildOverrideFederation(label string, seen *[]string) *Federation {
+
+	fed := NewFederation()
+
+	var productType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Product",
+			Fields: graphql.Fields{
+				"upc": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"price": &graphql.Field{
+					Type:       graphql.Int,
+					Extensions: Directives(OverrideLabel("legacy", label)),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if label, active := fed.OverrideActive(p); active {
+							*seen = append(*seen, label)
+						}
+						return 10, nil
+					},
+				},
+			},
+			Extensions: upcKey(),
+		},
+	)
+
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"product": &graphql.Field{
+			Type: productType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"upc": "1"}, nil
+			},
+		},
+	}, nil)
+
+	return fed
+}
+
+func TestOverrideLabelValidation(t *testing.T) {
+
+	tests := []struct {
+		label string
+		valid bool
+	}{
+		{"percent(25)", true},
+		{"percent(0)", true},
+		{"percent(100)", true},
+		{"percent(0.12345678)", true},
+		{"canary:eu-west.1", true},
+		{"percent(101)", false},
+		{"percent(1.123456789)", false},
+		{"percent(-1)", false},
+		{"percent()", false},
+		{"1canary", false},
+		{"can ary", false},
+	}
+	for _, test := range tests {
+		fed := buildOverrideFederation(test.label, nil)
+		if err := fed.Error(); (err == nil) != test.valid {
+			t.Errorf("label %q: unexpected validation result: %v", test.label, err)
+		}
+	}
+
+	fed := buildOverrideFederation("percent(25)", nil)
+	line := `  price: Int @override(from: "legacy", label: "percent(25)")`
+	sdl := fed.PrintSDL()
+	if !strings.Contains(sdl, line+"\n") || !strings.Contains(sdl, "/federation/v2.7") {
+		t.Errorf("unexpected sdl:\n%s", sdl)
+	}
+}
+
+func TestOverrideActive(t *testing.T) {
+
+	var seen []string
+	fed := buildOverrideFederation("percent(25)", &seen)
+
+	run := func(ctx context.Context) {
+		r := graphql.Do(graphql.Params{
+			Schema:        *fed.Schema(),
+			RequestString: `{ product { upc price } }`,
+			Context:       ctx,
+		})
+		if len(r.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", r.Errors)
+		}
+	}
+
+	run(context.Background())
+	run(WithOverrideLabels(context.Background(), "canary"))
+	if len(seen) != 0 {
+		t.Errorf("override reported active without its label: %v", seen)
+	}
+	run(WithOverrideLabels(context.Background(), "percent(25)"))
+	if len(seen) != 1 || seen[0] != "percent(25)" {
+		t.Errorf("override not reported active: %v", seen)
+	}
+
+	fed.SetOverrideRollout(func(ctx context.Context, label string) bool {
+		return true
+	})
+	run(context.Background())
+	if len(seen) != 2 {
+		t.Errorf("override rollout not used: %v", seen)
+	}
+}
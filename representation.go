@@ -0,0 +1,178 @@
+package gofed
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// entityFields returns the field definitions of the entity object or entity interface named typeName
+func (f *Federation) entityFields(typeName string) graphql.FieldDefinitionMap {
+	if obj, ok := f.objects[typeName]; ok {
+		return obj.Fields()
+	}
+	if iface := f.entityInterface(typeName); iface != nil {
+		return iface.Fields()
+	}
+	return nil
+}
+
+// coerceKeyFields checks the values of a representation against the fields
+// of the key it matched and coerces them to the key field types, numbers sent
+// as JSON become int or float64, IDs become strings and compound keys are
+// checked for missing or extra fields
+func (f *Federation) coerceKeyFields(typeName, key string, values map[string]interface{}) error {
+	set, err := parseFieldSet(key)
+	if err != nil {
+		return err
+	}
+	return coerceKeySelections(f.entityFields(typeName), typeName, set, values, false)
+}
+
+// coerceKeySelections coerces the values selected by a key selection set in
+// place, nested objects must not hold fields outside of the selection
+func coerceKeySelections(fields graphql.FieldDefinitionMap, path string, set *ast.SelectionSet, values map[string]interface{}, strict bool) error {
+	selected := make(map[string]bool, len(set.Selections))
+	for _, selection := range set.Selections {
+		field, ok := selection.(*ast.Field)
+		if !ok {
+			continue
+		}
+		name := field.Name.Value
+		selected[name] = true
+
+		definition, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("%s.%s: field is not defined", path, name)
+		}
+		value, ok := values[name]
+		if !ok {
+			return fmt.Errorf("%s.%s: missing key field", path, name)
+		}
+		coerced, err := coerceKeyValue(definition.Type, path+"."+name, field, value)
+		if err != nil {
+			return err
+		}
+		values[name] = coerced
+	}
+
+	if strict {
+		extra := make([]string, 0)
+		for name := range values {
+			if !selected[name] && name != "__typename" {
+				extra = append(extra, name)
+			}
+		}
+		if len(extra) > 0 {
+			sort.Strings(extra)
+			return fmt.Errorf("%s.%s: field is not part of the key", path, extra[0])
+		}
+	}
+	return nil
+}
+
+func coerceKeyValue(t graphql.Type, path string, field *ast.Field, value interface{}) (interface{}, error) {
+	if value == nil {
+		if _, ok := t.(*graphql.NonNull); ok {
+			return nil, fmt.Errorf("%s: must not be null", path)
+		}
+		return nil, nil
+	}
+
+	switch t := graphql.GetNullable(t).(type) {
+	case *graphql.Object:
+		values, ok := value.(map[string]interface{})
+		if !ok || field.SelectionSet == nil {
+			return nil, fmt.Errorf("%s: expected an object", path)
+		}
+		return values, coerceKeySelections(t.Fields(), path, field.SelectionSet, values, true)
+	case *graphql.Scalar:
+		if coerced := coerceScalar(t, value); coerced != nil {
+			return coerced, nil
+		}
+		return nil, fmt.Errorf("%s: invalid %s value %v", path, t.Name(), value)
+	case *graphql.Enum:
+		if coerced := t.ParseValue(value); coerced != nil {
+			return coerced, nil
+		}
+		return nil, fmt.Errorf("%s: invalid %s value %v", path, t.Name(), value)
+	default:
+		return nil, fmt.Errorf("%s: field cannot be part of a key", path)
+	}
+}
+
+// coerceScalar coerces a JSON value to a built in scalar, stricter than the
+// graphql-go input coercion which turns any value into a String or ID
+func coerceScalar(t *graphql.Scalar, value interface{}) interface{} {
+	switch t {
+	case graphql.Int:
+		switch v := jsonNumber(value).(type) {
+		case int64:
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				return int(v)
+			}
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+				return int(v)
+			}
+		}
+		return nil
+	case graphql.Float:
+		switch v := jsonNumber(value).(type) {
+		case int64:
+			return float64(v)
+		case float64:
+			return v
+		}
+		return nil
+	case graphql.String:
+		if v, ok := value.(string); ok {
+			return v
+		}
+		return nil
+	case graphql.ID:
+		switch v := jsonNumber(value).(type) {
+		case string:
+			return v
+		case int64:
+			return strconv.FormatInt(v, 10)
+		case float64:
+			if v == math.Trunc(v) {
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		return nil
+	case graphql.Boolean:
+		if v, ok := value.(bool); ok {
+			return v
+		}
+		return nil
+	}
+	return t.ParseValue(value)
+}
+
+// jsonNumber normalizes the numbers decoded from JSON or literals to int64 or float64
+func jsonNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return nil
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
@@ -0,0 +1,237 @@
+package gofed
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func buildRepresentationFederation(reps *[]*Representation, configure ...func(fed *Federation)) *Federation {
+
+	var regionType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Region",
+			Fields: graphql.Fields{
+				"code": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+		},
+	)
+
+	var storeType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Store",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+				"number": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"rating": &graphql.Field{
+					Type: graphql.Float,
+				},
+				"region": &graphql.Field{
+					Type: regionType,
+				},
+			},
+			Extensions: Directives(
+				&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "id"}},
+				&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "number rating region { code }"}},
+			),
+		},
+	)
+
+	fed := NewFederation()
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		*reps = append(*reps, rep)
+		return map[string]interface{}{"id": "1"}, nil
+	})
+	for _, c := range configure {
+		c(fed)
+	}
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"store": &graphql.Field{
+			Type: storeType,
+		},
+	}, nil)
+
+	return fed
+}
+
+func TestRepresentationCoercion(t *testing.T) {
+
+	var reps []*Representation
+	fed := buildRepresentationFederation(&reps)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	r := graphql.Do(graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: `query ($r: [_Any!]!) { _entities(representations: $r) { ... on Store { id } } }`,
+		VariableValues: map[string]interface{}{
+			"r": []interface{}{
+				map[string]interface{}{"__typename": "Store", "id": float64(7)},
+				map[string]interface{}{"__typename": "Store", "number": float64(3), "rating": float64(4), "region": map[string]interface{}{"code": "EU"}},
+			},
+		},
+	})
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	if len(reps) != 2 {
+		t.Fatalf("expected 2 representations, got %d", len(reps))
+	}
+	if reps[0].KeyValue != "7" {
+		t.Errorf("ID not coerced to a string: %#v", reps[0].KeyValue)
+	}
+	key, _ := reps[1].KeyValue.(map[string]interface{})
+	if key["number"] != 3 || key["rating"] != float64(4) {
+		t.Errorf("compound key not coerced: %#v", reps[1].KeyValue)
+	}
+
+	// literal representations are coerced the same way
+	reps = nil
+	r = graphql.Do(graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: `{ _entities(representations: [{__typename: "Store", number: 3, rating: 4, region: {code: "EU"}}]) { ... on Store { id } } }`,
+	})
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	key, _ = reps[0].KeyValue.(map[string]interface{})
+	if key["number"] != 3 || key["rating"] != float64(4) {
+		t.Errorf("compound key literal not coerced: %#v", reps[0].KeyValue)
+	}
+}
+
+func TestRepresentationValidation(t *testing.T) {
+
+	var reps []*Representation
+	fed := buildRepresentationFederation(&reps)
+
+	tests := []struct {
+		rep string
+		err string
+	}{
+		{`{"__typename": "Store", "id": true}`, "representation 0: invalid representation: Store.id: invalid ID value true"},
+		{`{"__typename": "Store", "id": null}`, "representation 0: invalid representation: Store.id: must not be null"},
+		{`{"__typename": "Store", "number": 1.5, "rating": 1, "region": {"code": "EU"}}`, "representation 0: invalid representation: Store.number: invalid Int value 1.5"},
+		{`{"__typename": "Store", "number": 1, "rating": 1, "region": "EU"}`, "representation 0: invalid representation: Store.region: expected an object"},
+		{`{"__typename": "Store", "number": 1, "rating": 1, "region": {}}`, "representation 0: invalid representation: Store.region.code: missing key field"},
+		{`{"__typename": "Store", "number": 1, "rating": 1, "region": {"code": "EU", "name": "Europe"}}`, "representation 0: invalid representation: Store.region.name: field is not part of the key"},
+		{`{"__typename": "Store", "number": 1}`, "representation 0: no @key matches the representation: Store"},
+	}
+	for _, test := range tests {
+		var rep map[string]interface{}
+		if err := json.Unmarshal([]byte(test.rep), &rep); err != nil {
+			t.Fatalf("invalid test representation %s", test.rep)
+		}
+		r := graphql.Do(graphql.Params{
+			Schema:         *fed.Schema(),
+			RequestString:  `query ($r: [_Any!]!) { _entities(representations: $r) { ... on Store { id } } }`,
+			VariableValues: map[string]interface{}{"r": []interface{}{rep}},
+		})
+		if len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Message, test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.rep, test.err, r.Errors)
+		}
+	}
+	if len(reps) != 0 {
+		t.Errorf("invalid representations reached the resolver: %d", len(reps))
+	}
+}
+
+type codedError struct{}
+
+func (codedError) Error() string { return "store is closed" }
+
+func (codedError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "STORE_CLOSED"}
+}
+
+func TestRepresentationPartialErrors(t *testing.T) {
+
+	var reps []*Representation
+	fed := buildRepresentationFederation(&reps)
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		switch rep.KeyValue {
+		case "2":
+			return nil, codedError{}
+		case "3":
+			return nil, nil
+		}
+		return map[string]interface{}{"id": rep.KeyValue}, nil
+	})
+
+	r := graphql.Do(graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: `query ($r: [_Any!]!) { _entities(representations: $r) { ... on Store { id } } }`,
+		VariableValues: map[string]interface{}{
+			"r": []interface{}{
+				map[string]interface{}{"__typename": "Store", "id": "1"},
+				map[string]interface{}{"__typename": "Store", "id": true},
+				map[string]interface{}{"__typename": "Store", "id": "2"},
+				map[string]interface{}{"__typename": "Store", "id": "3"},
+				map[string]interface{}{"__typename": "Store", "id": "4"},
+			},
+		},
+	})
+
+	data, _ := json.Marshal(r.Data)
+	if string(data) != `{"_entities":[{"id":"1"},null,null,null,{"id":"4"}]}` {
+		t.Errorf("unexpected data %s", data)
+	}
+
+	errs, _ := json.Marshal(r.Errors)
+	expected := `[` +
+		`{"message":"representation 1: invalid representation: Store.id: invalid ID value true","locations":[{"line":1,"column":24}],"path":["_entities",1],"extensions":{"code":"INVALID_REPRESENTATION"}},` +
+		`{"message":"representation 2: store is closed","locations":[{"line":1,"column":24}],"path":["_entities",2],"extensions":{"code":"STORE_CLOSED"}}` +
+		`]`
+	if string(errs) != expected {
+		t.Errorf("unexpected errors %s", errs)
+	}
+}
+
+func TestRepresentationBatchErrors(t *testing.T) {
+
+	var reps []*Representation
+	fed := buildRepresentationFederation(&reps)
+	fed.SetEntityBatchResolver(func(reps []*Representation) ([]interface{}, error) {
+		entities := make([]interface{}, 0, len(reps))
+		for _, rep := range reps {
+			if rep.KeyValue == "2" {
+				entities = append(entities, fmt.Errorf("store %s not found", rep.KeyValue))
+				continue
+			}
+			entities = append(entities, map[string]interface{}{"id": rep.KeyValue})
+		}
+		return entities, nil
+	})
+
+	r := graphql.Do(graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: `query ($r: [_Any!]!) { _entities(representations: $r) { ... on Store { id } } }`,
+		VariableValues: map[string]interface{}{
+			"r": []interface{}{
+				map[string]interface{}{"__typename": "Store", "id": "1"},
+				map[string]interface{}{"__typename": "Store", "id": "2"},
+			},
+		},
+	})
+
+	data, _ := json.Marshal(r.Data)
+	if string(data) != `{"_entities":[{"id":"1"},null]}` {
+		t.Errorf("unexpected data %s", data)
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Message != "representation 1: store 2 not found" || r.Errors[0].Extensions["code"] != "ENTITY_RESOLUTION_FAILED" {
+		t.Errorf("unexpected errors %v", r.Errors)
+	}
+	if path, _ := json.Marshal(r.Errors[0].Path); string(path) != `["_entities",1]` {
+		t.Errorf("unexpected error path %s", path)
+	}
+}
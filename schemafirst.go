@@ -0,0 +1,85 @@
+package gofed
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// BuildSubgraphSchemaFromSDL builds the subgraph schema from a federated SDL
+// document instead of graphql-go types. The document can use federation 1
+// extensions, like extend type and @external, or link federation 2 with
+// @link, including renamed imports. Field resolvers are bound from resolvers
+// by "Type.field", e.g. "Query.me", and entities resolve through
+// SetReferenceResolver or the entity resolvers. The _service SDL is the
+// document normalized, printed like a schema built from graphql-go types.
+func (f *Federation) BuildSubgraphSchemaFromSDL(sdl string, resolvers map[string]graphql.FieldResolveFn) *graphql.Schema {
+	doc, err := parseSDL(sdl, resolvers)
+	if err != nil {
+		f.err = err
+		return nil
+	}
+
+	schema := doc.build(f)
+	if f.err == nil {
+		f.err = checkResolverPaths(schema, resolvers)
+	}
+	return schema
+}
+
+// checkResolverPaths checks every resolver is bound to a field of the schema
+func checkResolverPaths(schema *graphql.Schema, resolvers map[string]graphql.FieldResolveFn) error {
+	paths := make([]string, 0, len(resolvers))
+	for path := range resolvers {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		parts := strings.SplitN(path, ".", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("resolver %q: expected a Type.field path", path)
+		}
+		obj, ok := schema.Type(parts[0]).(*graphql.Object)
+		if !ok {
+			return fmt.Errorf("resolver %q: %s is not an object type", path, parts[0])
+		}
+		if _, ok := obj.Fields()[parts[1]]; !ok {
+			return fmt.Errorf("resolver %q: %s has no field %s", path, parts[0], parts[1])
+		}
+	}
+	return nil
+}
+
+// SetReferenceResolver resolves the representations of the entity typeName
+// with resolverFn, instead of the resolvers set with SetEntityResolver and
+// SetEntityBatchResolver. It must be called before building the schema.
+func (f *Federation) SetReferenceResolver(typeName string, resolverFn EntityResolverFn) {
+	if f.typedResolvers == nil {
+		f.typedResolvers = make(map[string]typedEntityResolver)
+	}
+	f.typedResolvers[typeName] = referenceResolver(resolverFn)
+}
+
+// referenceResolver resolves every representation of an entity type in turn
+type referenceResolver EntityResolverFn
+
+func (r referenceResolver) resolve(ctx context.Context, reps []*Representation) []interface{} {
+	resolved := make([]interface{}, len(reps))
+	for i, rep := range reps {
+		entity, err := r(rep)
+		if err != nil {
+			resolved[i] = err
+			continue
+		}
+		resolved[i] = entity
+	}
+	return resolved
+}
+
+func (r referenceResolver) validate(typeName string, fields graphql.FieldDefinitionMap, keys []string) error {
+	return nil
+}
@@ -0,0 +1,178 @@
+package gofed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+const schemaFirstSDL = `
+extend schema
+  @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key", {name: "@shareable", as: "@shared"}, "@composeDirective"])
+  @link(url: "https://myorg.dev/cache/v1.0", import: ["@cacheTTL"])
+  @composeDirective(name: "@cacheTTL")
+
+directive @cacheTTL(seconds: Int!) on FIELD_DEFINITION | OBJECT
+
+"A product in the catalog"
+type Product @key(fields: "upc") {
+  upc: String!
+  "Whether the product can be ordered"
+  inStock: Boolean @cacheTTL(seconds: 30)
+  dimensions: Dimensions
+}
+
+type Dimensions @shared {
+  width: Float
+  unit: Unit
+}
+
+enum Unit {
+  CM
+  IN
+}
+
+type Query {
+  topProducts(first: Int = 5): [Product]
+}
+`
+
+func buildSchemaFirstFederation(sdl string, resolvers map[string]graphql.FieldResolveFn) *Federation {
+	fed := NewFederation()
+	fed.SetReferenceResolver("Product", func(rep *Representation) (interface{}, error) {
+		return map[string]interface{}{"upc": rep.KeyValue, "inStock": true}, nil
+	})
+	fed.BuildSubgraphSchemaFromSDL(sdl, resolvers)
+	return fed
+}
+
+func TestSchemaFirst(t *testing.T) {
+
+	fed := buildSchemaFirstFederation(schemaFirstSDL, map[string]graphql.FieldResolveFn{
+		"Query.topProducts": func(p graphql.ResolveParams) (interface{}, error) {
+			first, _ := p.Args["first"].(int)
+			products := make([]interface{}, 0, first)
+			for i := 0; i < first; i++ {
+				products = append(products, map[string]interface{}{"upc": string(rune('a' + i))})
+			}
+			return products, nil
+		},
+	})
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	r := graphql.Do(graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: `{ topProducts(first: 2) { upc } _entities(representations: [{__typename: "Product", upc: "p1"}]) { ... on Product { upc inStock } } }`,
+	})
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	data, _ := json.Marshal(r.Data)
+	if string(data) != `{"_entities":[{"inStock":true,"upc":"p1"}],"topProducts":[{"upc":"a"},{"upc":"b"}]}` {
+		t.Errorf("unexpected data %s", data)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`  @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@composeDirective", "@key", "@shareable"])`,
+		`  @link(url: "https://myorg.dev/cache/v1.0", import: ["@cacheTTL"])`,
+		`  @composeDirective(name: "@cacheTTL")`,
+		`directive @cacheTTL(seconds: Int!) on FIELD_DEFINITION | OBJECT`,
+		`" A product in the catalog"`,
+		`type Product @key(fields: "upc") {`,
+		`  inStock: Boolean @cacheTTL(seconds: 30)`,
+		`type Dimensions @shareable {`,
+		`  topProducts(first: Int = 5): [Product]`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+
+	// the normalized document builds the same schema
+	again := buildSchemaFirstFederation(sdl, nil)
+	if err := again.Error(); err != nil {
+		t.Fatalf("unexpected error rebuilding the sdl: %s", err)
+	}
+	if again.PrintSDL() != sdl {
+		t.Errorf("sdl changed when rebuilt:\n%s", again.PrintSDL())
+	}
+}
+
+func TestSchemaFirstService(t *testing.T) {
+
+	// _service serves the printed schema, it keeps what the document declares
+	fed := NewFederation()
+	fed.BuildSubgraphSchemaFromSDL(`
+extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key"])
+
+interface Priced {
+  price: Int
+}
+
+type Product implements Priced @key(fields: "upc") {
+  upc: String!
+  price: Int
+  sku: String @deprecated(reason: "Use upc")
+}
+
+type Dimensions {
+  width: Float
+}
+
+enum Unit {
+  CM
+  IN @deprecated
+}
+
+union SearchResult = Product | Dimensions
+
+type Query {
+  search(unit: Unit): [SearchResult]
+}
+`, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	r := graphql.Do(graphql.Params{Schema: *fed.Schema(), RequestString: `{ _service { sdl } }`})
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	sdl, _ := r.Data.(map[string]interface{})["_service"].(map[string]interface{})["sdl"].(string)
+	for _, line := range []string{
+		`type Product implements Priced @key(fields: "upc") {`,
+		`  sku: String @deprecated(reason: "Use upc")`,
+		`  IN @deprecated`,
+		`union SearchResult = Product | Dimensions`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+}
+
+func TestSchemaFirstErrors(t *testing.T) {
+
+	resolve := func(p graphql.ResolveParams) (interface{}, error) { return nil, nil }
+	tests := []struct {
+		sdl       string
+		resolvers map[string]graphql.FieldResolveFn
+		err       string
+	}{
+		{schemaFirstSDL, map[string]graphql.FieldResolveFn{"Query.products": resolve}, `resolver "Query.products": Query has no field products`},
+		{schemaFirstSDL, map[string]graphql.FieldResolveFn{"Unit.CM": resolve}, `resolver "Unit.CM": Unit is not an object type`},
+		{schemaFirstSDL, map[string]graphql.FieldResolveFn{"topProducts": resolve}, `resolver "topProducts": expected a Type.field path`},
+		{"type Query { me: User }", nil, "Query.me: unknown type User"},
+	}
+	for _, test := range tests {
+		fed := buildSchemaFirstFederation(test.sdl, test.resolvers)
+		if err := fed.Error(); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+}
@@ -2,8 +2,9 @@ package gofed
 
 import (
 	"fmt"
-	"os"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/graphql-go/graphql"
@@ -74,41 +75,165 @@ func findTypes(typeMap map[string]*graphql.Object, ifaces map[string]*graphql.In
 	}
 }
 
+// ExtensionStyle controls how types marked with @extends are printed
+type ExtensionStyle int
+
+const (
+	// ExtendTypeStyle prints extensions as "extend type User"
+	ExtendTypeStyle ExtensionStyle = iota
+	// ExtendsDirectiveStyle prints extensions as "type User @extends"
+	ExtendsDirectiveStyle
+)
+
+// sdlOptions holds the Federation settings that affect SDL printing
+type sdlOptions struct {
+	extensionStyle    ExtensionStyle
+	federationVersion string
+	repeatable        map[string]bool
+	composed          []*composedDirective
+	// migrate prints federation 1 schemas as federation 2
+	migrate bool
+	// rootTypes names the root operation types, set by printSDL
+	rootTypes map[string]bool
+	// minVersion is the lowest federation version linked
+	minVersion string
+	// objectDescriptions prints the descriptions of object types, kept for
+	// schemas printed from an SDL document
+	objectDescriptions bool
+}
+
 // printSDL - render the schema objec to a Federation compatible SDL
-func printSDL(schema *graphql.Schema, entityType *graphql.Union) (string, error) {
+func printSDL(schema *graphql.Schema, entityType *graphql.Union, opts *sdlOptions) (string, error) {
 	//fmt.Fprintln(os.Stdout, "printSDL")
 
 	var output strings.Builder
 
-	// type map to hold all types we can find
+	sites, err := collectDirectiveSites(schema)
+	if err != nil {
+		return "", err
+	}
+	version, imports := federationVersion(sites)
+
+	printOpts := sdlOptions{}
+	if opts != nil {
+		printOpts = *opts
+	}
+	printOpts.rootTypes = rootTypeNames(schema)
+
+	if printOpts.migrate {
+		version, imports = federationVersion(migrateSites(schema, entityType, sites))
+		if compareVersions(migrationVersion, version) > 0 {
+			version = migrationVersion
+		}
+	}
+
+	if printOpts.minVersion != "" && compareVersions(printOpts.minVersion, version) > 0 {
+		version = printOpts.minVersion
+	}
+
+	// composed directives need @composeDirective from federation 2.1
+	if len(printOpts.composed) > 0 {
+		if compareVersions(composeDirectiveVersion, version) > 0 {
+			version = composeDirectiveVersion
+		}
+		imports = append(imports, "@"+ComposeDirectiveDirective)
+		sort.Strings(imports)
+	}
+	printOpts.federationVersion = version
+
+	if version != federationV1 {
+		printLink(version, imports, &printOpts, &output)
+	}
+
+	// gather every user defined type in the schema by kind
 	typeMap := make(map[string]*graphql.Object)
 	interfaces := make(map[string]*graphql.Interface)
-
-	// recurse through entity types to gather possible types
-	for _, t := range entityType.Types() {
-		//fmt.Fprintln(os.Stdout, "111")
-		//fmt.Fprintln(os.Stdout, t.Name())
-		findTypes(typeMap, interfaces, t, false)
+	unions := make([]*graphql.Union, 0)
+	enums := make([]*graphql.Enum, 0)
+	inputs := make([]*graphql.InputObject, 0)
+	scalars := make([]*graphql.Scalar, 0)
+
+	for _, t := range sortTypeMap(schema.TypeMap()) {
+		if isSkippedType(schema, entityType, t.Name()) {
+			continue
+		}
+		// federation 2 subgraph SDL leaves out the plumbing added by gofed
+		if version != federationV1 && t.Name() == serviceType.Name() {
+			continue
+		}
+		switch t := t.(type) {
+		case *graphql.Object:
+			typeMap[t.Name()] = t
+		case *graphql.Interface:
+			interfaces[t.Name()] = t
+		case *graphql.Union:
+			unions = append(unions, t)
+		case *graphql.Enum:
+			enums = append(enums, t)
+		case *graphql.InputObject:
+			inputs = append(inputs, t)
+		case *graphql.Scalar:
+			scalars = append(scalars, t)
+		}
 	}
 
-	printUnion(entityType, &output)
-	printDirectives(schema.Directives(), &output)
+	if version == federationV1 && entityType != nil && len(entityType.Types()) > 0 {
+		if err := printUnion(entityType, &output); err != nil {
+			return "", err
+		}
+	}
+	if err := printDirectives(schema.Directives(), &printOpts, &output); err != nil {
+		return "", err
+	}
 
 	// print all interfaces
 	for _, v := range sortInterfaces(interfaces) {
-		printInterface(v, &output)
+		if err := printInterface(v, &printOpts, &output); err != nil {
+			return "", err
+		}
 	}
 
-	// also check query root in case we missed something in the entity types
-	findTypes(typeMap, interfaces, schema.QueryType(), true)
-	findTypes(typeMap, interfaces, schema.MutationType(), true)
-
 	for _, v := range sortObjects(typeMap) {
-		printType(v, &output)
+		if err := printType(v, &printOpts, &output); err != nil {
+			return "", err
+		}
 	}
 
-	printQuery(schema.QueryType(), &output)
-	printMutation(schema.MutationType(), &output)
+	for _, v := range unions {
+		if err := printUnion(v, &output); err != nil {
+			return "", err
+		}
+	}
+
+	for _, v := range enums {
+		if err := printEnum(v, &output); err != nil {
+			return "", err
+		}
+	}
+
+	for _, v := range inputs {
+		if err := printInputObject(v, &output); err != nil {
+			return "", err
+		}
+	}
+
+	for _, v := range scalars {
+		if err := printScalar(v, &output); err != nil {
+			return "", err
+		}
+	}
+
+	if err := printQuery(schema.QueryType(), version != federationV1, &output); err != nil {
+		return "", err
+	}
+	if err := printMutation(schema.MutationType(), &output); err != nil {
+		return "", err
+	}
+
+	// federation 2 subgraphs pull the federation definitions in with @link
+	if version != federationV1 {
+		return strings.TrimRight(output.String(), "\n"), nil
+	}
 
 	output.WriteString("\n\n")
 
@@ -118,11 +243,168 @@ func printSDL(schema *graphql.Schema, entityType *graphql.Union) (string, error)
 	return output.String(), nil
 }
 
-func printUnion(u *graphql.Union, out *strings.Builder) {
+// isSkippedType reports if a type is printed elsewhere or must not be printed at all
+func isSkippedType(schema *graphql.Schema, entityType *graphql.Union, name string) bool {
+	if strings.HasPrefix(name, "__") {
+		return true
+	}
+	switch name {
+	case "String", "Int", "Float", "Boolean", "ID", AnyType.Name():
+		return true
+	}
+	if entityType != nil && name == entityType.Name() {
+		return true
+	}
+	if q := schema.QueryType(); q != nil && name == q.Name() {
+		return true
+	}
+	if m := schema.MutationType(); m != nil && name == m.Name() {
+		return true
+	}
+	return false
+}
+
+// printLink writes the schema extension linking the federation 2 spec, along
+// with the specs and @composeDirective usages of composed directives
+func printLink(version string, imports []string, opts *sdlOptions, out *strings.Builder) {
+	fmt.Fprintf(out, "extend schema\n  @link(url: %s, import: %s)\n",
+		printValue(federationSpecURL+version), printValue(imports))
+	printComposedDirectives(opts.composed, out)
+	out.WriteString("\n")
+}
+
+// printDirectiveValues writes directive usages, space separated, with
+// arguments in the order the federation spec declares them
+func printDirectiveValues(extensions map[string]interface{}, out *strings.Builder) error {
+	directives, err := getDirectives(extensions)
+	if err != nil {
+		return err
+	}
+	printDirectiveList(directives, out)
+	return nil
+}
+
+func printDirectiveList(directives []*DirectiveValue, out *strings.Builder) {
+	for _, directive := range directives {
+		out.WriteString(" @")
+		out.WriteString(directive.Name)
+		if len(directive.Values) == 0 {
+			continue
+		}
+
+		args := make([]string, 0, len(directive.Values))
+		for k := range directive.Values {
+			args = append(args, k)
+		}
+		var order []string
+		if def, ok := federationDirectives[directive.Name]; ok {
+			order = def.args
+		}
+		sort.Slice(args, func(i, j int) bool {
+			return argIndex(order, args[i]) < argIndex(order, args[j]) ||
+				(argIndex(order, args[i]) == argIndex(order, args[j]) && args[i] < args[j])
+		})
+
+		out.WriteString("(")
+		for i, k := range args {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(k)
+			out.WriteString(": ")
+			out.WriteString(printValue(directive.Values[k]))
+		}
+		out.WriteString(")")
+	}
+}
+
+// argIndex returns the position of an argument in the spec order, unknown arguments sort last
+func argIndex(order []string, name string) int {
+	for i, v := range order {
+		if v == name {
+			return i
+		}
+	}
+	return len(order)
+}
+
+// printValue renders a Go value as a GraphQL literal
+func printValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		var b strings.Builder
+		b.WriteString("\"")
+		for _, r := range v {
+			switch r {
+			case '"':
+				b.WriteString("\\\"")
+			case '\\':
+				b.WriteString("\\\\")
+			case '\n':
+				b.WriteString("\\n")
+			case '\r':
+				b.WriteString("\\r")
+			case '\t':
+				b.WriteString("\\t")
+			default:
+				if r < 0x20 {
+					fmt.Fprintf(&b, "\\u%04x", r)
+				} else {
+					b.WriteRune(r)
+				}
+			}
+		}
+		b.WriteString("\"")
+		return b.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, printValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, printValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(v))
+		for _, k := range keys {
+			items = append(items, k+": "+printValue(v[k]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		// other slices, like the [][]string scopes of @requiresScopes
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			items := make([]string, 0, rv.Len())
+			for i := 0; i < rv.Len(); i++ {
+				items = append(items, printValue(rv.Index(i).Interface()))
+			}
+			return "[" + strings.Join(items, ", ") + "]"
+		}
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func printUnion(u *graphql.Union, out *strings.Builder) error {
 	if desc := u.Description(); desc != "" {
 		printDescription(desc, 0, out)
 	}
-	fmt.Fprintf(out, "union %s = ", u.Name())
+	fmt.Fprintf(out, "union %s", u.Name())
+	if err := printDirectiveValues(u.Extensions(), out); err != nil {
+		return fmt.Errorf("%s: %s", u.Name(), err)
+	}
+	out.WriteString(" = ")
 
 	typeNames := make([]string, 0, len(u.Types()))
 	for _, t := range u.Types() {
@@ -130,26 +412,28 @@ func printUnion(u *graphql.Union, out *strings.Builder) {
 	}
 	out.WriteString(strings.Join(typeNames, " | "))
 	out.WriteString("\n\n")
+	return nil
 }
 
-func printDirectives(d []*graphql.Directive, out *strings.Builder) error {
+// printDirectives writes the definitions of every directive declared in the
+// schema, skipping the directives built in to GraphQL
+func printDirectives(d []*graphql.Directive, opts *sdlOptions, out *strings.Builder) error {
 
 	for _, directive := range d {
+		if isSpecifiedDirective(directive) {
+			continue
+		}
 
+		if desc := directive.Description; desc != "" {
+			printDescription(desc, 0, out)
+		}
 		out.WriteString("directive @")
 		out.WriteString(directive.Name)
-		out.WriteString("(")
-
-		for _, arg := range directive.Args {
-			switch arg.Type.(type) {
-			case *graphql.List:
-				fmt.Fprintf(out, "%s: [%s]", arg.Name(), arg.Type.Name())
-			default:
-				fmt.Fprintf(out, "%s: %s", arg.Name(), arg.Type.Name())
-			}
-
+		printArgumentDefinitions(directive.Args, out)
+		if opts.repeatable[directive.Name] {
+			out.WriteString(" repeatable")
 		}
-		out.WriteString(") on ")
+		out.WriteString(" on ")
 		out.WriteString(strings.Join(directive.Locations, " | "))
 		out.WriteString("\n\n")
 
@@ -158,21 +442,75 @@ func printDirectives(d []*graphql.Directive, out *strings.Builder) error {
 	return nil
 }
 
-func printInterface(t *graphql.Interface, out *strings.Builder) error {
-	if desc := t.Description(); desc != "" {
-		printDescription(desc, 0, out)
+func isSpecifiedDirective(directive *graphql.Directive) bool {
+	for _, specified := range graphql.SpecifiedDirectives {
+		if directive.Name == specified.Name {
+			return true
+		}
 	}
+	return false
+}
 
-	out.WriteString("interface ")
-	out.WriteString(t.Name())
-	out.WriteString(" {\n")
+// printArgumentDefinitions writes a directive's arguments, on separate lines
+// when any of them has a description
+func printArgumentDefinitions(args []*graphql.Argument, out *strings.Builder) {
+	if len(args) == 0 {
+		return
+	}
 
-	for _, v := range sortFields(t.Fields()) {
-		printField(v, out)
+	sorted := make([]*graphql.Argument, len(args))
+	copy(sorted, args)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name() < sorted[j].Name()
+	})
+
+	multiline := false
+	for _, arg := range sorted {
+		if arg.Description() != "" {
+			multiline = true
+		}
 	}
 
-	out.WriteString("}\n\n")
-	return nil
+	out.WriteString("(")
+	for i, arg := range sorted {
+		if multiline {
+			out.WriteString("\n")
+			if desc := arg.Description(); desc != "" {
+				printDescription(desc, 2, out)
+			}
+			out.WriteString("  ")
+		} else if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(arg.Name())
+		out.WriteString(": ")
+		out.WriteString(arg.Type.String())
+		if arg.DefaultValue != nil {
+			out.WriteString(" = ")
+			out.WriteString(printDefaultValue(arg.Type, arg.DefaultValue))
+		}
+	}
+	if multiline {
+		out.WriteString("\n")
+	}
+	out.WriteString(")")
+}
+
+// printDefaultValue renders a default value, enum values are printed by name
+func printDefaultValue(t graphql.Input, v interface{}) string {
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		t = nonNull.OfType.(graphql.Input)
+	}
+	if enum, ok := t.(*graphql.Enum); ok {
+		if name, ok := enum.Serialize(v).(string); ok {
+			return name
+		}
+	}
+	return printValue(v)
+}
+
+func printInterface(t *graphql.Interface, opts *sdlOptions, out *strings.Builder) error {
+	return printFieldsType("interface", t.Name(), t.Description(), t.Extensions(), nil, t.Fields(), opts, out)
 }
 
 // sort fields in types so not so nondeterministic for testing
@@ -213,44 +551,135 @@ func sortInterfaces(objs map[string]*graphql.Interface) []*graphql.Interface {
 	return sorted
 }
 
-func printType(t *graphql.Object, out *strings.Builder) error {
-	if desc := t.Description(); desc != "" {
+func sortTypeMap(types graphql.TypeMap) []graphql.Type {
+	sorted := make([]graphql.Type, 0, len(types))
+	for _, v := range types {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name() < sorted[j].Name()
+	})
+	return sorted
+}
+
+func sortEnumValues(values []*graphql.EnumValueDefinition) []*graphql.EnumValueDefinition {
+	sorted := make([]*graphql.EnumValueDefinition, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func sortInputFields(fields graphql.InputObjectFieldMap) []*graphql.InputObjectField {
+	sorted := make([]*graphql.InputObjectField, 0, len(fields))
+	for _, v := range fields {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name() < sorted[j].Name()
+	})
+	return sorted
+}
+
+func printType(t *graphql.Object, opts *sdlOptions, out *strings.Builder) error {
+	desc := t.Description()
+	if opts.objectDescriptions {
+		// graphql-go's Object.Description always returns ""
+		desc = t.PrivateDescription
+	}
+	return printFieldsType("type", t.Name(), desc, t.Extensions(), t.Interfaces(), t.Fields(), opts, out)
+}
+
+// printFieldsType writes an object or interface definition with the interfaces
+// it implements, types marked with @extends are written as federation 1
+// extensions. Type extensions can't have a description, it is left out in the
+// extend type style.
+func printFieldsType(kind, name, desc string, extensions map[string]interface{}, interfaces []*graphql.Interface, fields graphql.FieldDefinitionMap, opts *sdlOptions, out *strings.Builder) error {
+	directives, err := getDirectives(extensions)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+
+	extension := hasDirective(directives, ExtendsDirective)
+	typeDirectives := directives
+	extendType := extension && !opts.migrate && opts.extensionStyle == ExtendTypeStyle
+	if opts.migrate {
+		directives = migrateTypeDirectives(kind, name, directives, opts.rootTypes)
+	}
+	if desc != "" && !extendType {
 		printDescription(desc, 0, out)
 	}
-	out.WriteString("type ")
-	out.WriteString(t.Name())
+	if extendType {
+		out.WriteString("extend ")
+		directives = withoutDirective(directives, ExtendsDirective)
+	}
 
-	for k, v := range t.Extensions() {
-		if k == "directives" {
-			//fmt.Fprintln(os.Stdout, "found directives")
-			obj, ok := v.([]*DirectiveValue)
-			if ok {
-				for _, directive := range obj {
-					//fmt.Fprintln(os.Stdout, "d", directive.Name)
-					out.WriteString(fmt.Sprintf(" @%s(", directive.Name))
-					for k, v := range directive.Values {
-						out.WriteString(k)
-						out.WriteString(": \"")
-						out.WriteString(fmt.Sprintf("%s", v))
-						out.WriteString("\")")
-					}
-				}
+	out.WriteString(kind)
+	out.WriteString(" ")
+	out.WriteString(name)
+	for i, iface := range interfaces {
+		if i == 0 {
+			out.WriteString(" implements ")
+		} else {
+			out.WriteString(" & ")
+		}
+		out.WriteString(iface.Name())
+	}
+	printDirectiveList(directives, out)
+	out.WriteString(" {\n")
 
-			} else {
-				fmt.Fprintln(os.Stdout, "directive assertion failed")
+	// federation 1 requires the key fields of an extension to be @external
+	externalFields := make(map[string]bool)
+	if extension && opts.federationVersion == federationV1 {
+		for _, key := range findDirectives(directives, KeyDirective) {
+			if fields, ok := key.Values["fields"].(string); ok {
+				for _, field := range topLevelKeyFields(fields) {
+					externalFields[field] = true
+				}
 			}
 		}
 	}
-	out.WriteString(" {\n")
-	for _, v := range sortFields(t.Fields()) {
-		printField(v, out)
+
+	for _, v := range sortFields(fields) {
+		var extra []*DirectiveValue
+		if externalFields[v.Name] {
+			if fieldDirectives, _ := getDirectives(v.Extensions); !hasDirective(fieldDirectives, ExternalDirective) {
+				extra = append(extra, External())
+			}
+		}
+		if opts.migrate {
+			fieldDirectives, err := getDirectives(v.Extensions)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %s", name, v.Name, err)
+			}
+			if err := printFieldWithDirectives(v, migrateFieldDirectives(typeDirectives, v.Name, fieldDirectives), out); err != nil {
+				return fmt.Errorf("%s.%s", name, err)
+			}
+			continue
+		}
+		if err := printField(v, out, extra...); err != nil {
+			return fmt.Errorf("%s.%s", name, err)
+		}
 	}
 
 	out.WriteString("}\n\n")
 	return nil
 }
 
-func printField(f *graphql.FieldDefinition, out *strings.Builder) {
+func printField(f *graphql.FieldDefinition, out *strings.Builder, extra ...*DirectiveValue) error {
+	fieldDirectives, err := getDirectives(f.Extensions)
+	if err != nil {
+		return fmt.Errorf("%s: %s", f.Name, err)
+	}
+	directives := make([]*DirectiveValue, 0, len(fieldDirectives)+len(extra))
+	directives = append(directives, fieldDirectives...)
+	directives = append(directives, extra...)
+	return printFieldWithDirectives(f, directives, out)
+}
+
+// printFieldWithDirectives writes a field definition with the given directives
+func printFieldWithDirectives(f *graphql.FieldDefinition, directives []*DirectiveValue, out *strings.Builder) error {
 	if desc := f.Description; desc != "" {
 		printDescription(desc, 2, out)
 	}
@@ -271,6 +700,13 @@ func printField(f *graphql.FieldDefinition, out *strings.Builder) {
 			default:
 				out.WriteString(arg.Type.Name())
 			}
+			if arg.DefaultValue != nil {
+				out.WriteString(" = ")
+				out.WriteString(printDefaultValue(arg.Type, arg.DefaultValue))
+			}
+			if err := printDirectiveValues(arg.Extensions, out); err != nil {
+				return fmt.Errorf("%s(%s:): %s", f.Name, arg.Name(), err)
+			}
 			if i < len(f.Args)-1 {
 				out.WriteString(", ")
 			}
@@ -289,36 +725,139 @@ func printField(f *graphql.FieldDefinition, out *strings.Builder) {
 		out.WriteString(f.Type.Name())
 	}
 
-	// TODO: add args
+	printDeprecation(f.DeprecationReason, out)
+	printDirectiveList(directives, out)
 	out.WriteString("\n")
+	return nil
 }
 
-func printQuery(t *graphql.Object, out *strings.Builder) {
-	if t == nil {
+// printDeprecation writes the @deprecated directive of a field or enum value,
+// without a reason when it is the default one
+func printDeprecation(reason string, out *strings.Builder) {
+	if reason == "" {
 		return
 	}
+	deprecated := &DirectiveValue{Name: "deprecated", Values: map[string]interface{}{}}
+	if reason != graphql.DefaultDeprecationReason {
+		deprecated.Values["reason"] = reason
+	}
+	printDirectiveList([]*DirectiveValue{deprecated}, out)
+}
+
+func printEnum(t *graphql.Enum, out *strings.Builder) error {
 	if desc := t.Description(); desc != "" {
 		printDescription(desc, 0, out)
 	}
-	out.WriteString("type Query {\n")
+	out.WriteString("enum ")
+	out.WriteString(t.Name())
+	if err := printDirectiveValues(t.Extensions(), out); err != nil {
+		return fmt.Errorf("%s: %s", t.Name(), err)
+	}
+	out.WriteString(" {\n")
+	for _, v := range sortEnumValues(t.Values()) {
+		if desc := v.Description; desc != "" {
+			printDescription(desc, 2, out)
+		}
+		out.WriteString("  ")
+		out.WriteString(v.Name)
+		printDeprecation(v.DeprecationReason, out)
+		if err := printDirectiveValues(v.Extensions, out); err != nil {
+			return fmt.Errorf("%s.%s: %s", t.Name(), v.Name, err)
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("}\n\n")
+	return nil
+}
+
+func printInputObject(t *graphql.InputObject, out *strings.Builder) error {
+	if desc := t.Description(); desc != "" {
+		printDescription(desc, 0, out)
+	}
+	out.WriteString("input ")
+	out.WriteString(t.Name())
+	if err := printDirectiveValues(t.Extensions(), out); err != nil {
+		return fmt.Errorf("%s: %s", t.Name(), err)
+	}
+	out.WriteString(" {\n")
+	for _, v := range sortInputFields(t.Fields()) {
+		if desc := v.Description(); desc != "" {
+			printDescription(desc, 2, out)
+		}
+		out.WriteString("  ")
+		out.WriteString(v.Name())
+		out.WriteString(": ")
+		out.WriteString(v.Type.String())
+		if v.DefaultValue != nil {
+			out.WriteString(" = ")
+			out.WriteString(printDefaultValue(v.Type, v.DefaultValue))
+		}
+		if err := printDirectiveValues(v.Extensions, out); err != nil {
+			return fmt.Errorf("%s.%s: %s", t.Name(), v.Name(), err)
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("}\n\n")
+	return nil
+}
+
+func printScalar(t *graphql.Scalar, out *strings.Builder) error {
+	if desc := t.Description(); desc != "" {
+		printDescription(desc, 0, out)
+	}
+	out.WriteString("scalar ")
+	out.WriteString(t.Name())
+	if err := printDirectiveValues(t.Extensions(), out); err != nil {
+		return fmt.Errorf("%s: %s", t.Name(), err)
+	}
+	out.WriteString("\n\n")
+	return nil
+}
+
+// printQuery writes the query type, without _entities and _service when
+// skipFederationFields is set
+func printQuery(t *graphql.Object, skipFederationFields bool, out *strings.Builder) error {
+	if t == nil {
+		return nil
+	}
+	fields := make([]*graphql.FieldDefinition, 0, len(t.Fields()))
 	for _, v := range sortFields(t.Fields()) {
-		printField(v, out)
+		if skipFederationFields && (v.Name == "_entities" || v.Name == "_service") {
+			continue
+		}
+		fields = append(fields, v)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	if desc := t.Description(); desc != "" {
+		printDescription(desc, 0, out)
+	}
+	out.WriteString("type Query {\n")
+	for _, v := range fields {
+		if err := printField(v, out); err != nil {
+			return fmt.Errorf("Query.%s", err)
+		}
 	}
 	out.WriteString("}\n\n")
+	return nil
 }
 
-func printMutation(t *graphql.Object, out *strings.Builder) {
+func printMutation(t *graphql.Object, out *strings.Builder) error {
 	if t == nil || len(t.Fields()) == 0 {
-		return
+		return nil
 	}
 	if desc := t.Description(); desc != "" {
 		printDescription(desc, 0, out)
 	}
 	out.WriteString("type Mutation {\n")
 	for _, v := range sortFields(t.Fields()) {
-		printField(v, out)
+		if err := printField(v, out); err != nil {
+			return fmt.Errorf("Mutation.%s", err)
+		}
 	}
 	out.WriteString("}\n\n")
+	return nil
 }
 
 func printDescription(desc string, indent int, out *strings.Builder) {
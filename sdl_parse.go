@@ -0,0 +1,879 @@
+package gofed
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// the directives of "extend schema" are parsed as the directives of a scalar with this name
+const schemaExtensionName = "_SchemaExtension"
+
+// sdlDocument holds the graphql-go types built from a federated SDL document
+type sdlDocument struct {
+	types          map[string]graphql.Type
+	queryFields    graphql.Fields
+	mutationFields graphql.Fields
+	directives     []*graphql.Directive
+	repeatable     map[string]bool
+	// links holds the @link directives of the schema extension
+	links []*DirectiveValue
+	// federationVersion is the version of the linked federation spec
+	federationVersion string
+	// composed holds the directives kept with @composeDirective
+	composed []*composedDirective
+}
+
+// sortedTypes returns the named types of the document sorted by name
+func (d *sdlDocument) sortedTypes() []graphql.Type {
+	types := make([]graphql.Type, 0, len(d.types))
+	for _, t := range d.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name() < types[j].Name()
+	})
+	return types
+}
+
+// newFederation creates a Federation declaring the document's custom
+// directives and builds its subgraph schema
+func (d *sdlDocument) newFederation() *Federation {
+	fed := NewFederation()
+	d.build(fed)
+	return fed
+}
+
+// build declares the document's custom directives on f and builds its subgraph schema
+func (d *sdlDocument) build(f *Federation) *graphql.Schema {
+	for _, directive := range d.directives {
+		if d.repeatable[directive.Name] {
+			f.AddRepeatableDirective(directive)
+		} else {
+			f.AddDirective(directive)
+		}
+	}
+	for _, c := range d.composed {
+		f.ComposeDirective(c.name, c.specURL)
+	}
+	f.fromSDL = true
+	f.linkVersion = d.federationVersion
+	f.types = d.sortedTypes()
+	return f.BuildSubgraphSchema(d.queryFields, d.mutationFields)
+}
+
+// sdlBuilder turns the definitions of a parsed SDL document into graphql-go types
+type sdlBuilder struct {
+	doc        *sdlDocument
+	objects    map[string]*ast.ObjectDefinition
+	extensions map[string]bool
+	interfaces map[string]*ast.InterfaceDefinition
+	inputs     map[string]*ast.InputObjectDefinition
+	// type names in document order
+	objectNames    []string
+	interfaceNames []string
+	inputNames     []string
+	fields         map[string]graphql.Fields
+	rootTypes      map[string]string
+	// aliases maps the local name of imported federation directives to their spec name
+	aliases map[string]string
+	// importedTypes holds the local names of the types imported from the federation spec
+	importedTypes map[string]bool
+	specPrefix    string
+	resolvers     map[string]graphql.FieldResolveFn
+	definitions   []ast.Node
+}
+
+// parseSDL parses a federated SDL document into graphql-go types. Field
+// resolvers are looked up in resolvers by "Type.field".
+func parseSDL(sdl string, resolvers map[string]graphql.FieldResolveFn) (*sdlDocument, error) {
+	source, repeatable := preprocessSDL(sdl)
+	astDoc, err := parser.Parse(parser.ParseParams{Source: source})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &sdlBuilder{
+		doc: &sdlDocument{
+			types:      make(map[string]graphql.Type),
+			repeatable: repeatable,
+		},
+		objects:       make(map[string]*ast.ObjectDefinition),
+		extensions:    make(map[string]bool),
+		interfaces:    make(map[string]*ast.InterfaceDefinition),
+		inputs:        make(map[string]*ast.InputObjectDefinition),
+		fields:        make(map[string]graphql.Fields),
+		rootTypes:     map[string]string{"query": "Query", "mutation": "Mutation"},
+		aliases:       make(map[string]string),
+		importedTypes: make(map[string]bool),
+		specPrefix:    "federation__",
+		resolvers:     resolvers,
+		definitions:   astDoc.Definitions,
+	}
+	if err := b.build(); err != nil {
+		return nil, err
+	}
+	return b.doc, nil
+}
+
+// graphql-go's parser predates a few SDL features used by federated schemas,
+// preprocessSDL rewrites "extend schema", "extend interface" and repeatable
+// directive definitions into equivalent syntax it understands, returning the
+// names of the repeatable directives. Only the definitions of the document
+// are rewritten, strings, descriptions and comments are left untouched.
+func preprocessSDL(sdl string) (string, map[string]bool) {
+	repeatable := make(map[string]bool)
+	tokens := tokenizeSDL(sdl)
+	token := func(i int) string {
+		if i < len(tokens) {
+			return tokens[i].value
+		}
+		return ""
+	}
+
+	var out strings.Builder
+	last, depth := 0, 0
+	replace := func(start, end int, value string) {
+		out.WriteString(sdl[last:start])
+		out.WriteString(value)
+		last = end
+	}
+	for i := 0; i < len(tokens); i++ {
+		switch token(i) {
+		case "{":
+			depth++
+			continue
+		case "}":
+			depth--
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+		switch {
+		case token(i) == "extend" && token(i+1) == "schema":
+			replace(tokens[i].start, tokens[i+1].end, "scalar "+schemaExtensionName)
+			i++
+		case token(i) == "extend" && token(i+1) == "interface" && isSDLName(token(i+2)):
+			replace(tokens[i].start, tokens[i+2].end, "interface "+token(i+2)+" @"+ExtendsDirective)
+			i += 2
+		case token(i) == "directive" && token(i+1) == "@" && isSDLName(token(i+2)):
+			name := token(i + 2)
+			i += 3
+			if token(i) == "(" {
+				for parens := 0; i < len(tokens); i++ {
+					if token(i) == "(" {
+						parens++
+					} else if token(i) == ")" {
+						parens--
+					}
+					if parens == 0 {
+						i++
+						break
+					}
+				}
+			}
+			if token(i) == "repeatable" {
+				repeatable[name] = true
+				replace(tokens[i-1].end, tokens[i].end, "")
+			}
+		}
+	}
+	out.WriteString(sdl[last:])
+	return out.String(), repeatable
+}
+
+// sdlToken is a name, number or punctuator of an SDL document, start and end
+// are its byte offsets
+type sdlToken struct {
+	value      string
+	start, end int
+}
+
+// tokenizeSDL splits an SDL document into tokens, each string and block string
+// becomes a single `"` token and comments are skipped
+func tokenizeSDL(sdl string) []sdlToken {
+	var tokens []sdlToken
+	isNameChar := func(c byte) bool {
+		return c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+	}
+	for i := 0; i < len(sdl); {
+		start := i
+		switch c := sdl[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+			continue
+		case c == '#':
+			for i < len(sdl) && sdl[i] != '\n' && sdl[i] != '\r' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(sdl[i:], `"""`):
+			i += 3
+			for i < len(sdl) && !strings.HasPrefix(sdl[i:], `"""`) {
+				if strings.HasPrefix(sdl[i:], `\"""`) {
+					i += 3
+				}
+				i++
+			}
+			i = minInt(i+3, len(sdl))
+			tokens = append(tokens, sdlToken{value: `"`, start: start, end: i})
+		case c == '"':
+			i++
+			for i < len(sdl) && sdl[i] != '"' && sdl[i] != '\n' {
+				if sdl[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i = minInt(i+1, len(sdl))
+			tokens = append(tokens, sdlToken{value: `"`, start: start, end: i})
+		case isNameChar(c):
+			for i < len(sdl) && isNameChar(sdl[i]) {
+				i++
+			}
+			tokens = append(tokens, sdlToken{value: sdl[start:i], start: start, end: i})
+		default:
+			i++
+			tokens = append(tokens, sdlToken{value: sdl[start:i], start: start, end: i})
+		}
+	}
+	return tokens
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// isSDLName reports if a token is a name rather than a number or punctuator
+func isSDLName(token string) bool {
+	return token != "" && (token[0] == '_' || token[0] >= 'A' && token[0] <= 'Z' || token[0] >= 'a' && token[0] <= 'z')
+}
+
+// federationTypeNames are the types of the federation and link specs that
+// gofed adds itself instead of building them from a schema
+var federationTypeNames = map[string]bool{
+	"_Any":          true,
+	"_Entity":       true,
+	"_Service":      true,
+	"_FieldSet":     true,
+	"link__Import":  true,
+	"link__Purpose": true,
+}
+
+// isFederationTypeName reports if a type is part of the federation or link
+// specs, and added by gofed instead of being built from the document.
+// Introspection types are reserved and skipped too.
+func isFederationTypeName(name string) bool {
+	return federationTypeNames[name] || name == schemaExtensionName ||
+		strings.HasPrefix(name, "federation__") || strings.HasPrefix(name, "__")
+}
+
+// isFederationType also matches the federation types under their local
+// names: the prefix of the @link, the imported types, and FieldSet in a
+// federation 1 document
+func (b *sdlBuilder) isFederationType(name string) bool {
+	if isFederationTypeName(name) || strings.HasPrefix(name, b.specPrefix) || b.importedTypes[name] {
+		return true
+	}
+	return name == "FieldSet" && b.doc.federationVersion == ""
+}
+
+// isFederationDirectiveName reports if a directive definition belongs to the
+// federation or link specs, or is built into GraphQL
+func isFederationDirectiveName(name string) bool {
+	if _, ok := federationDirectives[name]; ok {
+		return true
+	}
+	for _, d := range graphql.SpecifiedDirectives {
+		if d.Name == name {
+			return true
+		}
+	}
+	return name == "link" || name == ComposeDirectiveDirective || strings.Contains(name, "__")
+}
+
+func (b *sdlBuilder) build() error {
+	if err := b.collect(); err != nil {
+		return err
+	}
+
+	// create every named type first, fields are filled in afterwards so types
+	// can reference each other
+	for _, def := range b.definitions {
+		var err error
+		switch def := def.(type) {
+		case *ast.EnumDefinition:
+			err = b.addType(def.Name.Value, b.enumType(def))
+		case *ast.ScalarDefinition:
+			err = b.addType(def.Name.Value, b.scalarType(def))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range b.interfaceNames {
+		if err := b.addType(name, b.interfaceType(b.interfaces[name])); err != nil {
+			return err
+		}
+	}
+	for _, name := range b.objectNames {
+		if b.isRoot(name) {
+			continue
+		}
+		if err := b.addType(name, b.objectType(b.objects[name])); err != nil {
+			return err
+		}
+	}
+	for _, name := range b.inputNames {
+		if err := b.addType(name, b.inputType(b.inputs[name])); err != nil {
+			return err
+		}
+	}
+	for _, def := range b.definitions {
+		if def, ok := def.(*ast.UnionDefinition); ok && !b.isFederationType(def.Name.Value) {
+			union, err := b.unionType(def)
+			if err != nil {
+				return err
+			}
+			if err := b.addType(def.Name.Value, union); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range b.interfaceNames {
+		fields, err := b.buildFields(name, b.interfaces[name].Fields)
+		if err != nil {
+			return err
+		}
+		b.fields[name] = fields
+	}
+	for _, name := range b.objectNames {
+		defs := b.objects[name].Fields
+		if name == b.rootTypes["query"] {
+			// _entities and _service are added back by BuildSubgraphSchema
+			defs = make([]*ast.FieldDefinition, 0, len(b.objects[name].Fields))
+			for _, def := range b.objects[name].Fields {
+				if def.Name.Value != "_entities" && def.Name.Value != "_service" {
+					defs = append(defs, def)
+				}
+			}
+		}
+		fields, err := b.buildFields(name, defs)
+		if err != nil {
+			return err
+		}
+		switch {
+		case name == b.rootTypes["query"]:
+			b.doc.queryFields = fields
+		case name == b.rootTypes["mutation"]:
+			b.doc.mutationFields = fields
+		default:
+			b.fields[name] = fields
+		}
+	}
+	for _, name := range b.inputNames {
+		for _, field := range b.inputs[name].Fields {
+			if _, err := b.inputTypeRef(field.Type); err != nil {
+				return fmt.Errorf("%s.%s: %s", name, field.Name.Value, err)
+			}
+		}
+	}
+	if b.doc.queryFields == nil {
+		b.doc.queryFields = graphql.Fields{}
+	}
+
+	for _, def := range b.definitions {
+		if def, ok := def.(*ast.DirectiveDefinition); ok && !isFederationDirectiveName(def.Name.Value) {
+			directive, err := b.directive(def)
+			if err != nil {
+				return err
+			}
+			b.doc.directives = append(b.doc.directives, directive)
+		}
+	}
+	return nil
+}
+
+// collect gathers the definitions of the document, merging type extensions
+// into their types and reading the @link imports of the schema extension
+func (b *sdlBuilder) collect() error {
+	for _, def := range b.definitions {
+		switch def := def.(type) {
+		case *ast.SchemaDefinition:
+			for _, op := range def.OperationTypes {
+				b.rootTypes[op.Operation] = op.Type.Name.Value
+			}
+		case *ast.ScalarDefinition:
+			if def.Name.Value == schemaExtensionName {
+				b.readLinks(def.Directives)
+			}
+		}
+	}
+	if _, ok := b.rootTypes["subscription"]; ok {
+		return fmt.Errorf("subscriptions are not supported")
+	}
+
+	for _, def := range b.definitions {
+		switch def := def.(type) {
+		case *ast.ObjectDefinition:
+			b.addObject(def, false)
+		case *ast.TypeExtensionDefinition:
+			b.addObject(def.Definition, true)
+		case *ast.InterfaceDefinition:
+			if existing, ok := b.interfaces[def.Name.Value]; ok {
+				existing.Fields = append(existing.Fields, def.Fields...)
+				existing.Directives = append(existing.Directives, def.Directives...)
+			} else if !b.isFederationType(def.Name.Value) {
+				b.interfaces[def.Name.Value] = def
+				b.interfaceNames = append(b.interfaceNames, def.Name.Value)
+			}
+		case *ast.InputObjectDefinition:
+			if _, ok := b.inputs[def.Name.Value]; ok {
+				return fmt.Errorf("%s is defined more than once", def.Name.Value)
+			}
+			if !b.isFederationType(def.Name.Value) {
+				b.inputs[def.Name.Value] = def
+				b.inputNames = append(b.inputNames, def.Name.Value)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *sdlBuilder) addObject(def *ast.ObjectDefinition, extension bool) {
+	name := def.Name.Value
+	if b.isFederationType(name) {
+		return
+	}
+	if extension && !b.isRoot(name) {
+		b.extensions[name] = true
+	}
+	existing, ok := b.objects[name]
+	if !ok {
+		// copied, as extensions of the same type are merged into it
+		copied := *def
+		b.objects[name] = &copied
+		b.objectNames = append(b.objectNames, name)
+		return
+	}
+	existing.Interfaces = append(existing.Interfaces, def.Interfaces...)
+	existing.Directives = append(existing.Directives, def.Directives...)
+	existing.Fields = append(existing.Fields, def.Fields...)
+	if existing.Description == nil {
+		existing.Description = def.Description
+	}
+}
+
+func (b *sdlBuilder) isRoot(name string) bool {
+	return name == b.rootTypes["query"] || name == b.rootTypes["mutation"]
+}
+
+// readLinks records the @link directives of the schema extension, the
+// local names of the federation directives they import and the directives
+// kept with @composeDirective
+func (b *sdlBuilder) readLinks(directives []*ast.Directive) {
+	for _, d := range directives {
+		if d.Name.Value != "link" {
+			continue
+		}
+		link := &DirectiveValue{Name: "link", Values: argumentValues(d.Arguments)}
+		b.doc.links = append(b.doc.links, link)
+
+		url, _ := link.Values["url"].(string)
+		if !strings.HasPrefix(url, federationSpecURL) {
+			continue
+		}
+		b.doc.federationVersion = strings.TrimPrefix(url, federationSpecURL)
+		if as, ok := link.Values["as"].(string); ok {
+			b.specPrefix = as + "__"
+		}
+		imports, _ := link.Values["import"].([]interface{})
+		for _, imported := range imports {
+			switch imported := imported.(type) {
+			case string:
+				if !strings.HasPrefix(imported, "@") {
+					b.importedTypes[imported] = true
+				}
+			case map[string]interface{}:
+				name, _ := imported["name"].(string)
+				as, _ := imported["as"].(string)
+				if name != "" && as != "" {
+					b.aliases[strings.TrimPrefix(as, "@")] = strings.TrimPrefix(name, "@")
+				}
+				if !strings.HasPrefix(name, "@") {
+					if as == "" {
+						as = name
+					}
+					b.importedTypes[as] = true
+				}
+			}
+		}
+	}
+
+	for _, d := range directives {
+		if b.directiveName(d.Name.Value) != ComposeDirectiveDirective {
+			continue
+		}
+		name, _ := argumentValues(d.Arguments)["name"].(string)
+		b.doc.composed = append(b.doc.composed, &composedDirective{
+			name:    strings.TrimPrefix(name, "@"),
+			specURL: b.linkedSpec(name),
+		})
+	}
+}
+
+// linkedSpec returns the url of the @link importing a custom directive, or
+// whose prefix the directive is named with
+func (b *sdlBuilder) linkedSpec(name string) string {
+	name = strings.TrimPrefix(name, "@")
+	for _, link := range b.doc.links {
+		url, _ := link.Values["url"].(string)
+		if strings.HasPrefix(url, federationSpecURL) {
+			continue
+		}
+		imports, _ := link.Values["import"].([]interface{})
+		for _, imported := range imports {
+			if imported == "@"+name {
+				return url
+			}
+		}
+		if specName, _, err := parseSpecURL(url); err == nil && strings.HasPrefix(name, specName+"__") {
+			return url
+		}
+	}
+	return ""
+}
+
+// directiveName returns the spec name of a directive used in the document
+func (b *sdlBuilder) directiveName(name string) string {
+	if specName, ok := b.aliases[name]; ok {
+		return specName
+	}
+	return strings.TrimPrefix(name, b.specPrefix)
+}
+
+// directiveExtensions converts directive usages into a graphql-go extensions
+// map, @deprecated is returned separately as graphql-go handles it itself
+func (b *sdlBuilder) directiveExtensions(directives []*ast.Directive, extra ...*DirectiveValue) (map[string]interface{}, string) {
+	values := make([]*DirectiveValue, 0, len(directives)+len(extra))
+	deprecationReason := ""
+	for _, d := range directives {
+		if d.Name.Value == "deprecated" {
+			deprecationReason = graphql.DefaultDeprecationReason
+			if reason, ok := argumentValues(d.Arguments)["reason"].(string); ok {
+				deprecationReason = reason
+			}
+			continue
+		}
+		values = append(values, &DirectiveValue{
+			Name:   b.directiveName(d.Name.Value),
+			Values: argumentValues(d.Arguments),
+		})
+	}
+	values = append(values, extra...)
+	if len(values) == 0 {
+		return nil, deprecationReason
+	}
+	return Directives(values...), deprecationReason
+}
+
+func argumentValues(args []*ast.Argument) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		values[arg.Name.Value] = valueFromAST(arg.Value)
+	}
+	return values
+}
+
+func (b *sdlBuilder) addType(name string, t graphql.Type) error {
+	if b.isFederationType(name) {
+		return nil
+	}
+	if _, ok := b.doc.types[name]; ok {
+		return fmt.Errorf("%s is defined more than once", name)
+	}
+	b.doc.types[name] = t
+	return nil
+}
+
+// description returns a description without the surrounding whitespace
+// printDescription pads it with
+func description(value *ast.StringValue) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(value.Value)
+}
+
+func (b *sdlBuilder) objectType(def *ast.ObjectDefinition) *graphql.Object {
+	name := def.Name.Value
+	var extra []*DirectiveValue
+	if b.extensions[name] {
+		extra = append(extra, Extends())
+		for _, d := range def.Directives {
+			if b.directiveName(d.Name.Value) == ExtendsDirective {
+				extra = nil
+			}
+		}
+	}
+	extensions, _ := b.directiveExtensions(def.Directives, extra...)
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        name,
+		Description: description(def.Description),
+		Extensions:  extensions,
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return b.fields[name]
+		}),
+		Interfaces: (graphql.InterfacesThunk)(func() []*graphql.Interface {
+			interfaces := make([]*graphql.Interface, 0, len(def.Interfaces))
+			for _, named := range def.Interfaces {
+				if iface, ok := b.doc.types[named.Name.Value].(*graphql.Interface); ok {
+					interfaces = append(interfaces, iface)
+				}
+			}
+			return interfaces
+		}),
+	})
+}
+
+func (b *sdlBuilder) interfaceType(def *ast.InterfaceDefinition) *graphql.Interface {
+	name := def.Name.Value
+	extensions, _ := b.directiveExtensions(def.Directives)
+	return graphql.NewInterface(graphql.InterfaceConfig{
+		Name:        name,
+		Description: description(def.Description),
+		Extensions:  extensions,
+		ResolveType: b.resolveType,
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return b.fields[name]
+		}),
+	})
+}
+
+func (b *sdlBuilder) unionType(def *ast.UnionDefinition) (*graphql.Union, error) {
+	members := make([]*graphql.Object, 0, len(def.Types))
+	for _, named := range def.Types {
+		member, ok := b.doc.types[named.Name.Value].(*graphql.Object)
+		if !ok {
+			return nil, fmt.Errorf("%s: member %s is not an object type", def.Name.Value, named.Name.Value)
+		}
+		members = append(members, member)
+	}
+	extensions, _ := b.directiveExtensions(def.Directives)
+	return graphql.NewUnion(graphql.UnionConfig{
+		Name:        def.Name.Value,
+		Description: description(def.Description),
+		Extensions:  extensions,
+		Types:       members,
+		ResolveType: b.resolveType,
+	}), nil
+}
+
+// resolveType resolves interfaces and unions built from SDL with the
+// __typename of map values
+func (b *sdlBuilder) resolveType(p graphql.ResolveTypeParams) *graphql.Object {
+	if value, ok := p.Value.(map[string]interface{}); ok {
+		if typeName, ok := value["__typename"].(string); ok {
+			obj, _ := b.doc.types[typeName].(*graphql.Object)
+			return obj
+		}
+	}
+	return nil
+}
+
+func (b *sdlBuilder) enumType(def *ast.EnumDefinition) *graphql.Enum {
+	values := make(graphql.EnumValueConfigMap, len(def.Values))
+	for _, v := range def.Values {
+		extensions, deprecationReason := b.directiveExtensions(v.Directives)
+		values[v.Name.Value] = &graphql.EnumValueConfig{
+			Value:             v.Name.Value,
+			Description:       description(v.Description),
+			DeprecationReason: deprecationReason,
+			Extensions:        extensions,
+		}
+	}
+	extensions, _ := b.directiveExtensions(def.Directives)
+	return graphql.NewEnum(graphql.EnumConfig{
+		Name:        def.Name.Value,
+		Description: description(def.Description),
+		Extensions:  extensions,
+		Values:      values,
+	})
+}
+
+// scalarType builds a custom scalar passing values through unchanged
+func (b *sdlBuilder) scalarType(def *ast.ScalarDefinition) *graphql.Scalar {
+	identity := func(value interface{}) interface{} {
+		return value
+	}
+	extensions, _ := b.directiveExtensions(def.Directives)
+	return graphql.NewScalar(graphql.ScalarConfig{
+		Name:         def.Name.Value,
+		Description:  description(def.Description),
+		Extensions:   extensions,
+		Serialize:    identity,
+		ParseValue:   identity,
+		ParseLiteral: valueFromAST,
+	})
+}
+
+func (b *sdlBuilder) inputType(def *ast.InputObjectDefinition) *graphql.InputObject {
+	extensions, _ := b.directiveExtensions(def.Directives)
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        def.Name.Value,
+		Description: description(def.Description),
+		Extensions:  extensions,
+		Fields: (graphql.InputObjectConfigFieldMapThunk)(func() graphql.InputObjectConfigFieldMap {
+			fields := make(graphql.InputObjectConfigFieldMap, len(def.Fields))
+			for _, field := range def.Fields {
+				fieldType, _ := b.inputTypeRef(field.Type)
+				fieldExtensions, _ := b.directiveExtensions(field.Directives)
+				fields[field.Name.Value] = &graphql.InputObjectFieldConfig{
+					Type:         fieldType,
+					DefaultValue: valueFromAST(field.DefaultValue),
+					Description:  description(field.Description),
+					Extensions:   fieldExtensions,
+				}
+			}
+			return fields
+		}),
+	})
+}
+
+func (b *sdlBuilder) directive(def *ast.DirectiveDefinition) (*graphql.Directive, error) {
+	args, err := b.arguments("@"+def.Name.Value, def.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]string, 0, len(def.Locations))
+	for _, location := range def.Locations {
+		locations = append(locations, location.Value)
+	}
+	return graphql.NewDirective(graphql.DirectiveConfig{
+		Name:        def.Name.Value,
+		Description: description(def.Description),
+		Args:        args,
+		Locations:   locations,
+	}), nil
+}
+
+func (b *sdlBuilder) buildFields(parent string, defs []*ast.FieldDefinition) (graphql.Fields, error) {
+	fields := make(graphql.Fields, len(defs))
+	for _, def := range defs {
+		name := def.Name.Value
+		path := parent + "." + name
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("%s is defined more than once", path)
+		}
+		fieldType, err := b.outputTypeRef(def.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		args, err := b.arguments(path, def.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		extensions, deprecationReason := b.directiveExtensions(def.Directives)
+		fields[name] = &graphql.Field{
+			Name:              name,
+			Type:              fieldType,
+			Args:              args,
+			Description:       description(def.Description),
+			DeprecationReason: deprecationReason,
+			Extensions:        extensions,
+			Resolve:           b.resolvers[path],
+		}
+	}
+	return fields, nil
+}
+
+func (b *sdlBuilder) arguments(path string, defs []*ast.InputValueDefinition) (graphql.FieldConfigArgument, error) {
+	args := make(graphql.FieldConfigArgument, len(defs))
+	for _, def := range defs {
+		argType, err := b.inputTypeRef(def.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s(%s:): %s", path, def.Name.Value, err)
+		}
+		extensions, _ := b.directiveExtensions(def.Directives)
+		args[def.Name.Value] = &graphql.ArgumentConfig{
+			Type:         argType,
+			DefaultValue: valueFromAST(def.DefaultValue),
+			Description:  description(def.Description),
+			Extensions:   extensions,
+		}
+	}
+	return args, nil
+}
+
+// typeRef returns the graphql-go type for a type reference, with its list and
+// non-null wrappers
+func (b *sdlBuilder) typeRef(t ast.Type) (graphql.Type, error) {
+	switch t := t.(type) {
+	case *ast.List:
+		ofType, err := b.typeRef(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewList(ofType), nil
+	case *ast.NonNull:
+		ofType, err := b.typeRef(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewNonNull(ofType), nil
+	case *ast.Named:
+		switch name := t.Name.Value; name {
+		case "String":
+			return graphql.String, nil
+		case "Int":
+			return graphql.Int, nil
+		case "Float":
+			return graphql.Float, nil
+		case "Boolean":
+			return graphql.Boolean, nil
+		case "ID":
+			return graphql.ID, nil
+		default:
+			if named, ok := b.doc.types[name]; ok {
+				return named, nil
+			}
+			return nil, fmt.Errorf("unknown type %s", name)
+		}
+	}
+	return nil, fmt.Errorf("invalid type reference")
+}
+
+func (b *sdlBuilder) outputTypeRef(t ast.Type) (graphql.Output, error) {
+	ref, err := b.typeRef(t)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := graphql.GetNamed(ref).(*graphql.InputObject); ok {
+		return nil, fmt.Errorf("input type %s cannot be used as an output type", ref.Name())
+	}
+	return ref.(graphql.Output), nil
+}
+
+func (b *sdlBuilder) inputTypeRef(t ast.Type) (graphql.Input, error) {
+	ref, err := b.typeRef(t)
+	if err != nil {
+		return nil, err
+	}
+	switch graphql.GetNamed(ref).(type) {
+	case *graphql.Object, *graphql.Interface, *graphql.Union:
+		return nil, fmt.Errorf("output type %s cannot be used as an input type", ref.Name())
+	}
+	return ref.(graphql.Input), nil
+}
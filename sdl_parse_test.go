@@ -0,0 +1,181 @@
+package gofed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSDL(t *testing.T) {
+
+	doc, err := parseSDL(`
+directive @cacheTTL(seconds: Int!) repeatable on FIELD_DEFINITION
+
+type Product @key(fields: "upc") {
+  upc: String!
+  price: Int @cacheTTL(seconds: 30)
+  kind: Kind
+}
+
+enum Kind {
+  BOOK
+  MOVIE
+}
+
+extend interface Media @key(fields: "id") {
+  id: ID!
+}
+
+type Query {
+  topProducts(first: Int = 5): [Product]
+}
+`, nil)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	if !doc.repeatable["cacheTTL"] {
+		t.Error("cacheTTL is not marked repeatable")
+	}
+	for _, name := range []string{"Product", "Kind", "Media"} {
+		if _, ok := doc.types[name]; !ok {
+			t.Errorf("type %s was not built", name)
+		}
+	}
+	if _, ok := doc.queryFields["topProducts"]; !ok {
+		t.Error("query field topProducts was not built")
+	}
+
+	fed := doc.newFederation()
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`type Product @key(fields: "upc") {`,
+		`  price: Int @cacheTTL(seconds: 30)`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+}
+
+func TestParseSDLErrors(t *testing.T) {
+
+	tests := []struct {
+		sdl string
+		err string
+	}{
+		{"type Query { me: User }", "Query.me: unknown type User"},
+		{"type Query { me: String", "Syntax Error"},
+	}
+	for _, test := range tests {
+		if _, err := parseSDL(test.sdl, nil); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+}
+
+func TestPreprocessSDL(t *testing.T) {
+
+	sdl, repeatable := preprocessSDL(`
+extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key"])
+
+"Cached by the gateway, see directive @cache(maxAge: Int) repeatable"
+directive @cache(maxAge: Int = 60, scope: String = "(public)") repeatable on FIELD_DEFINITION
+
+# extend interface Ignored
+extend interface Media @key(fields: "id") {
+  """
+  This does not extend interface Foo or extend schema.
+  """
+  id: ID!
+  extend: String
+}
+`)
+	if !repeatable["cache"] || len(repeatable) != 1 {
+		t.Errorf("unexpected repeatable directives %v", repeatable)
+	}
+	for _, s := range []string{
+		"scalar " + schemaExtensionName + ` @link(`,
+		`"Cached by the gateway, see directive @cache(maxAge: Int) repeatable"`,
+		`scope: String = "(public)") on FIELD_DEFINITION`,
+		"# extend interface Ignored\n",
+		"interface Media @extends @key(",
+		"This does not extend interface Foo or extend schema.",
+		"  extend: String\n",
+	} {
+		if !strings.Contains(sdl, s) {
+			t.Errorf("preprocessed sdl is missing %q:\n%s", s, sdl)
+		}
+	}
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchemaFromSDL(`
+type Product @key(fields: "upc") {
+  "Does not extend interface Foo or extend schema"
+  upc: String!
+}
+
+type Query {
+  product: Product
+}
+`, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+	if sdl := fed.PrintSDL(); !strings.Contains(sdl, "Does not extend interface Foo or extend schema\"\n") {
+		t.Errorf("description was rewritten:\n%s", sdl)
+	}
+}
+
+func TestParseSDLFederationTypes(t *testing.T) {
+
+	link := `extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key", "FieldSet"])
+`
+	types := `
+scalar FieldSet
+scalar link__Import
+scalar federation__Scope
+
+type _Internal {
+  id: ID
+}
+
+type Audit__Entry {
+  id: ID
+}
+
+type Query {
+  internal: _Internal
+  entries: [Audit__Entry]
+}
+`
+	tests := []struct {
+		sdl     string
+		kept    []string
+		dropped []string
+	}{
+		{link + types, []string{"_Internal", "Audit__Entry"}, []string{"FieldSet", "link__Import", "federation__Scope"}},
+		// FieldSet is a user type when the federation 2 link doesn't import it
+		{strings.Replace(link, `, "FieldSet"`, "", 1) + types, []string{"FieldSet", "_Internal", "Audit__Entry"}, nil},
+		// federation 1 documents declare the spec's FieldSet without a link
+		{types, []string{"_Internal", "Audit__Entry"}, []string{"FieldSet"}},
+	}
+	for i, test := range tests {
+		doc, err := parseSDL(test.sdl, nil)
+		if err != nil {
+			t.Errorf("test %d: unexpected parse error: %s", i, err)
+			continue
+		}
+		for _, name := range test.kept {
+			if _, ok := doc.types[name]; !ok {
+				t.Errorf("test %d: type %s was not built", i, name)
+			}
+		}
+		for _, name := range test.dropped {
+			if _, ok := doc.types[name]; ok {
+				t.Errorf("test %d: federation type %s was built", i, name)
+			}
+		}
+	}
+}
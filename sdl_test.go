@@ -1,6 +1,7 @@
 package gofed
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/graphql-go/graphql"
@@ -128,10 +129,285 @@ func TestSDLPrint(t *testing.T) {
 	}
 
 	//sdl, err := printSDL(schema, enentityType)
-	printSDL(schema, entityType)
+	printSDL(schema, entityType, nil)
 
 	//fmt.Fprintln(os.Stdout, result)
 	//if !reflect.DeepEqual(result.Data, expected) {
 	//	t.Errorf("wrong result, query: %v, graphql result diff: %v", query, testutil.Diff(expected, result))
 	//}
 }
+
+func buildExtensionFederation(style ExtensionStyle) *Federation {
+
+	var userType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "User",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+				"reviews": &graphql.Field{
+					Type: graphql.NewList(graphql.String),
+				},
+			},
+			Extensions: Directives(
+				&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "id"}},
+				Extends(),
+			),
+		},
+	)
+
+	fed := NewFederation()
+	fed.SetExtensionStyle(style)
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"me": &graphql.Field{
+			Type: userType,
+		},
+	}, nil)
+
+	return fed
+}
+
+func TestSDLPrintExtensions(t *testing.T) {
+
+	sdl := buildExtensionFederation(ExtendTypeStyle).PrintSDL()
+	if !strings.Contains(sdl, "extend type User @key(fields: \"id\") {\n  id: ID! @external\n  reviews: [String]\n}") {
+		t.Errorf("extension not printed with extend type:\n%s", sdl)
+	}
+
+	sdl = buildExtensionFederation(ExtendsDirectiveStyle).PrintSDL()
+	if !strings.Contains(sdl, "type User @key(fields: \"id\") @extends {\n  id: ID! @external\n") {
+		t.Errorf("extension not printed with @extends:\n%s", sdl)
+	}
+	if strings.Contains(sdl, "extend type") {
+		t.Errorf("@extends style should not print extend type:\n%s", sdl)
+	}
+}
+
+func TestSDLPrintExtensionDescriptions(t *testing.T) {
+
+	const extensionSDL = `
+"A user of the shop"
+type User @key(fields: "id") @extends {
+  id: ID! @external
+  reviews: [String]
+}
+
+type Query {
+  me: User
+}
+`
+	fed := NewFederation()
+	fed.SetExtensionStyle(ExtendsDirectiveStyle)
+	fed.BuildSubgraphSchemaFromSDL(extensionSDL, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+	if sdl := fed.PrintSDL(); !strings.Contains(sdl, "\" A user of the shop\"\ntype User @key(fields: \"id\") @extends {\n") {
+		t.Errorf("description not printed with @extends:\n%s", sdl)
+	}
+
+	// type extensions can't have a description
+	fed = NewFederation()
+	fed.SetExtensionStyle(ExtendTypeStyle)
+	fed.BuildSubgraphSchemaFromSDL(extensionSDL, nil)
+	if sdl := fed.PrintSDL(); strings.Contains(sdl, "A user of the shop") || !strings.Contains(sdl, "extend type User") {
+		t.Errorf("description printed with extend type:\n%s", sdl)
+	}
+}
+
+func TestSDLPrintDirectives(t *testing.T) {
+
+	schema, entityType := buildTestSchema()
+	sdl, err := printSDL(schema, entityType, nil)
+	if err != nil {
+		t.Fatalf("error printing sdl: %s", err)
+	}
+
+	if !strings.Contains(sdl, "directive @blah(fields: String) on OBJECT | INTERFACE\n") {
+		t.Errorf("custom directive not printed:\n%s", sdl)
+	}
+	for _, builtin := range []string{"@include", "@skip", "@deprecated"} {
+		if strings.Contains(sdl, "directive "+builtin) {
+			t.Errorf("built in directive %s should not be printed", builtin)
+		}
+	}
+
+	cacheDirective := graphql.NewDirective(graphql.DirectiveConfig{
+		Name:        "cache",
+		Description: "Cache the value of a type.",
+		Args: graphql.FieldConfigArgument{
+			"maxAge": &graphql.ArgumentConfig{
+				Type:         graphql.NewNonNull(graphql.Int),
+				Description:  "Maximum age in seconds.",
+				DefaultValue: 60,
+			},
+			"scope": &graphql.ArgumentConfig{
+				Type: graphql.String,
+			},
+		},
+		Locations: []string{graphql.DirectiveLocationObject, graphql.DirectiveLocationFieldDefinition},
+	})
+	traceDirective := graphql.NewDirective(graphql.DirectiveConfig{
+		Name: "trace",
+		Args: graphql.FieldConfigArgument{
+			"label": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+		},
+		Locations: []string{graphql.DirectiveLocationField},
+	})
+
+	buildFed := func(extensions map[string]interface{}) *Federation {
+		fed := NewFederation()
+		fed.AddRepeatableDirective(cacheDirective)
+		fed.AddDirective(traceDirective)
+		fed.BuildSubgraphSchema(graphql.Fields{
+			"selfie": &graphql.Field{
+				Type: graphql.NewObject(graphql.ObjectConfig{
+					Name: "Selfie",
+					Fields: graphql.Fields{
+						"url": &graphql.Field{
+							Type: graphql.String,
+						},
+					},
+					Extensions: extensions,
+				}),
+			},
+		}, nil)
+		return fed
+	}
+
+	fed := buildFed(Directives(&DirectiveValue{Name: "cache", Values: map[string]interface{}{"scope": "PUBLIC", "maxAge": 30}}))
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl = fed.PrintSDL()
+	for _, expected := range []string{
+		"\" Cache the value of a type.\"\ndirective @cache(\n  \" Maximum age in seconds.\"\n  maxAge: Int! = 60\n  scope: String\n) repeatable on OBJECT | FIELD_DEFINITION\n",
+		"directive @trace(label: String!) on FIELD\n",
+		"type Selfie @cache(maxAge: 30, scope: \"PUBLIC\") {\n",
+	} {
+		if !strings.Contains(sdl, expected) {
+			t.Errorf("sdl is missing %q:\n%s", expected, sdl)
+		}
+	}
+
+	fed = buildFed(Directives(&DirectiveValue{Name: "trace", Values: map[string]interface{}{"label": "x"}}))
+	if err := fed.Error(); err == nil || err.Error() != "Selfie: @trace cannot be used on OBJECT" {
+		t.Errorf("expected location error, got: %v", err)
+	}
+}
+
+func TestSDLPrintLeavesOutFederationFields(t *testing.T) {
+
+	productType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Product",
+		Fields: graphql.Fields{
+			"upc": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"name": &graphql.Field{
+				Type:       graphql.String,
+				Extensions: Directives(Shareable()),
+			},
+		},
+		Extensions: Directives(&DirectiveValue{Name: KeyDirective, Values: map[string]interface{}{"fields": "upc"}}),
+	})
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"product": &graphql.Field{
+			Type: productType,
+		},
+	}, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	// the SDL only references types it declares
+	sdl := fed.PrintSDL()
+	for _, plumbing := range []string{"_Entity", "_Service", "_entities", "_service", "_Any"} {
+		if strings.Contains(sdl, plumbing) {
+			t.Errorf("federation 2 sdl contains %s:\n%s", plumbing, sdl)
+		}
+	}
+	if !strings.Contains(sdl, "type Query {\n  product: Product\n}") {
+		t.Errorf("sdl is missing the query type:\n%s", sdl)
+	}
+}
+
+func TestSDLPrintUnionsAndInputDefaults(t *testing.T) {
+
+	unitEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Unit",
+		Values: graphql.EnumValueConfigMap{
+			"CM": &graphql.EnumValueConfig{
+				Value: "cm",
+			},
+			"INCH": &graphql.EnumValueConfig{
+				Value: "inch",
+			},
+		},
+	})
+	filterInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"unit": &graphql.InputObjectFieldConfig{
+				Type:         unitEnum,
+				DefaultValue: "cm",
+			},
+		},
+	})
+	bookType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Book",
+		Fields: graphql.Fields{
+			"title": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+	movieType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Movie",
+		Fields: graphql.Fields{
+			"title": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+	searchUnion := graphql.NewUnion(graphql.UnionConfig{
+		Name:       "SearchResult",
+		Types:      []*graphql.Object{bookType, movieType},
+		Extensions: Directives(Tag("public")),
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			return bookType
+		},
+	})
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"search": &graphql.Field{
+			Type: graphql.NewList(searchUnion),
+			Args: graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{
+					Type: filterInput,
+				},
+			},
+		},
+	}, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`union SearchResult @tag(name: "public") = Book | Movie`,
+		`input Filter {`,
+		`  unit: Unit = CM`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package gofed
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/graphql-go/graphql"
+)
+
+// ID is a string mapped to the GraphQL ID scalar by ObjectFromStruct
+type ID string
+
+// ObjectFromStruct builds a graphql-go object from a Go struct, or a pointer
+// to one. Exported fields become GraphQL fields named by their graphql tag,
+// their json tag or their lower camel cased Go name, a "-" tag skips them.
+// Pointers and slices are nullable, other fields non null. Structs referenced
+// by fields become objects too.
+//
+// The gofed tag sets federation directives: key, external, shareable,
+// inaccessible, requires=<fields>, provides=<fields>, override=<subgraph> and
+// tag=<name>, e.g. `gofed:"key"`. Fields tagged key make up the first @key of
+// the object. A blank field sets the name, directives and description of the
+// object itself, more keys are added there with key=<fields>:
+//
+//	_ struct{} `graphql:"Product" gofed:"shareable,key=sku" description:"A product"`
+//
+// Every field gets a resolver reading its Go field and the object an IsTypeOf
+// matching the struct, so it resolves in the _Entity union. The objects are
+// kept by f, a struct referenced by several others maps to one object of its
+// schema.
+func (f *Federation) ObjectFromStruct(v interface{}) (*graphql.Object, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ObjectFromStruct: %T is not a struct", v)
+	}
+
+	f.structMu.Lock()
+	defer f.structMu.Unlock()
+	if f.structObjects == nil {
+		f.structObjects = make(map[reflect.Type]*graphql.Object)
+	}
+	return f.structObject(t)
+}
+
+// structObject builds the object of a struct type, structMu must be locked
+func (f *Federation) structObject(t reflect.Type) (*graphql.Object, error) {
+	if obj, ok := f.structObjects[t]; ok {
+		return obj, nil
+	}
+
+	name := t.Name()
+	description := ""
+	typeDirectives := make([]*DirectiveValue, 0)
+	keyFields := make([]string, 0)
+	for _, field := range reflect.VisibleFields(t) {
+		if field.Name == "_" {
+			if tag := field.Tag.Get("graphql"); tag != "" {
+				name = tag
+			}
+			description = field.Tag.Get("description")
+			directives, err := structDirectives(field.Tag.Get("gofed"), true)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", t.Name(), err)
+			}
+			typeDirectives = append(typeDirectives, directives...)
+			continue
+		}
+		if fieldName, ok := structFieldName(field); ok && hasTagOption(field.Tag.Get("gofed"), "key") {
+			keyFields = append(keyFields, fieldName)
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("ObjectFromStruct: anonymous struct needs a graphql tag on a blank field")
+	}
+	if len(keyFields) > 0 {
+		typeDirectives = append([]*DirectiveValue{Key(strings.Join(keyFields, " "))}, typeDirectives...)
+	}
+
+	// fields are filled in after the object is cached, so structs can reference each other
+	fields := graphql.Fields{}
+	config := graphql.ObjectConfig{
+		Name: name,
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return fields
+		}),
+		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+			vt := reflect.TypeOf(p.Value)
+			return vt == t || vt == reflect.PtrTo(t)
+		},
+		Description: description,
+	}
+	if len(typeDirectives) > 0 {
+		config.Extensions = Directives(typeDirectives...)
+	}
+	obj := graphql.NewObject(config)
+	f.structObjects[t] = obj
+
+	for _, field := range reflect.VisibleFields(t) {
+		fieldName, ok := structFieldName(field)
+		if !ok {
+			continue
+		}
+		fieldType, err := f.structFieldType(field.Type, true)
+		if err != nil {
+			delete(f.structObjects, t)
+			return nil, fmt.Errorf("%s.%s: %s", name, fieldName, err)
+		}
+		directives, err := structDirectives(field.Tag.Get("gofed"), false)
+		if err != nil {
+			delete(f.structObjects, t)
+			return nil, fmt.Errorf("%s.%s: %s", name, fieldName, err)
+		}
+		fields[fieldName] = &graphql.Field{
+			Type:        fieldType,
+			Description: field.Tag.Get("description"),
+			Resolve:     structFieldResolver(field.Index),
+		}
+		if len(directives) > 0 {
+			fields[fieldName].Extensions = Directives(directives...)
+		}
+	}
+	return obj, nil
+}
+
+// structFieldName returns the GraphQL name of a struct field, false for
+// fields left out of the object
+func structFieldName(field reflect.StructField) (string, bool) {
+	if field.Name == "_" || field.Anonymous || !field.IsExported() {
+		return "", false
+	}
+	for _, tag := range []string{"graphql", "json"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+	return lowerCamel(field.Name), true
+}
+
+// lowerCamel lower cases the leading upper case run of a Go name, "UserID"
+// becomes "userID" and "URLPath" "urlPath"
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// structFieldType maps a Go type to a GraphQL output type
+func (f *Federation) structFieldType(t reflect.Type, nonNull bool) (graphql.Output, error) {
+	var output graphql.Output
+	switch t.Kind() {
+	case reflect.Ptr:
+		return f.structFieldType(t.Elem(), false)
+	case reflect.Slice, reflect.Array:
+		elem, err := f.structFieldType(t.Elem(), true)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewList(elem), nil
+	case reflect.String:
+		output = graphql.String
+		if t == reflect.TypeOf(ID("")) {
+			output = graphql.ID
+		}
+	case reflect.Bool:
+		output = graphql.Boolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		output = graphql.Int
+	case reflect.Float32, reflect.Float64:
+		output = graphql.Float
+	case reflect.Struct:
+		obj, err := f.structObject(t)
+		if err != nil {
+			return nil, err
+		}
+		output = obj
+	default:
+		return nil, fmt.Errorf("unsupported Go type %s", t)
+	}
+	if nonNull {
+		return graphql.NewNonNull(output), nil
+	}
+	return output, nil
+}
+
+// structFieldResolver resolves a field from the Go struct field at index
+func structFieldResolver(index []int) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		v := reflect.ValueOf(p.Source)
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return graphql.DefaultResolveFn(p)
+		}
+		field, err := v.FieldByIndexErr(index)
+		if err != nil {
+			// a nil embedded struct pointer
+			return nil, nil
+		}
+		for field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				return nil, nil
+			}
+			field = field.Elem()
+		}
+		if field.Kind() == reflect.Slice && field.IsNil() {
+			return nil, nil
+		}
+		return field.Interface(), nil
+	}
+}
+
+// structDirectives parses the directives of a gofed struct tag
+func structDirectives(tag string, object bool) ([]*DirectiveValue, error) {
+	directives := make([]*DirectiveValue, 0)
+	if tag == "" {
+		return directives, nil
+	}
+	for _, option := range strings.Split(tag, ",") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(option), "=")
+		switch {
+		case name == "key" && !object && !hasValue:
+			// key fields are gathered into the @key of the object
+		case name == "key" && object && hasValue:
+			directives = append(directives, Key(value))
+		case name == "shareable" && !hasValue:
+			directives = append(directives, Shareable())
+		case name == "inaccessible" && !hasValue:
+			directives = append(directives, Inaccessible())
+		case name == "tag" && hasValue:
+			directives = append(directives, Tag(value))
+		case name == "extends" && object && !hasValue:
+			directives = append(directives, Extends())
+		case name == "interfaceObject" && object && !hasValue:
+			directives = append(directives, InterfaceObject())
+		case name == "external" && !object && !hasValue:
+			directives = append(directives, External())
+		case name == "requires" && !object && hasValue:
+			directives = append(directives, Requires(value))
+		case name == "provides" && !object && hasValue:
+			directives = append(directives, Provides(value))
+		case name == "override" && !object && hasValue:
+			directives = append(directives, Override(value))
+		default:
+			return nil, fmt.Errorf("invalid gofed tag option %q", option)
+		}
+	}
+	return directives, nil
+}
+
+// hasTagOption reports if a comma separated struct tag holds option
+func hasTagOption(tag, option string) bool {
+	for _, o := range strings.Split(tag, ",") {
+		if strings.TrimSpace(o) == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package gofed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+type structWarehouse struct {
+	_    struct{} `graphql:"Warehouse" gofed:"shareable"`
+	Code string
+}
+
+type structProduct struct {
+	_          struct{} `graphql:"Product" gofed:"key=sku" description:"A product"`
+	UPC        ID       `graphql:"upc" gofed:"key"`
+	SKU        string   `json:"sku"`
+	Name       *string
+	PriceCents int              `gofed:"external"`
+	Weight     float64          `gofed:"external"`
+	Shipping   int              `gofed:"requires=priceCents weight"`
+	Tags       []string         `gofed:"inaccessible"`
+	Warehouse  *structWarehouse `gofed:"provides=code"`
+	Related    []*structProduct `description:"Products bought together"`
+	Internal   string           `graphql:"-"`
+	hidden     string
+}
+
+func TestObjectFromStruct(t *testing.T) {
+
+	fed := NewFederation()
+	productType, err := fed.ObjectFromStruct(&structProduct{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if again, _ := fed.ObjectFromStruct(structProduct{}); again != productType {
+		t.Errorf("the same struct should map to the same object")
+	}
+	if other, _ := NewFederation().ObjectFromStruct(structProduct{}); other == productType {
+		t.Errorf("objects should not be shared between federations")
+	}
+
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		return &structProduct{UPC: ID(rep.KeyValue.(string)), SKU: "s1", Related: []*structProduct{{UPC: "2"}}}, nil
+	})
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"product": &graphql.Field{
+			Type: productType,
+		},
+	}, nil)
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`type Product @key(fields: "upc") @key(fields: "sku") {`,
+		`  name: String`,
+		`  priceCents: Int! @external`,
+		`  related: [Product]`,
+		`  shipping: Int! @requires(fields: "priceCents weight")`,
+		`  tags: [String!] @inaccessible`,
+		`  upc: ID!`,
+		`  warehouse: Warehouse @provides(fields: "code")`,
+		`type Warehouse @shareable {`,
+		`  code: String!`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+	if strings.Contains(sdl, "internal") || strings.Contains(sdl, "hidden") {
+		t.Errorf("skipped fields printed in sdl:\n%s", sdl)
+	}
+
+	r := graphql.Do(graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: `{ _entities(representations: [{__typename: "Product", upc: "1"}]) { ... on Product { upc sku name related { upc } } } }`,
+	})
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	data, _ := json.Marshal(r.Data)
+	if string(data) != `{"_entities":[{"name":null,"related":[{"upc":"2"}],"sku":"s1","upc":"1"}]}` {
+		t.Errorf("unexpected data %s", data)
+	}
+}
+
+func TestObjectFromStructErrors(t *testing.T) {
+
+	type badTag struct {
+		ID string `gofed:"keys"`
+	}
+	type fieldKey struct {
+		ID string `gofed:"key=id"`
+	}
+	type badType struct {
+		Lookup map[string]string
+	}
+
+	tests := []struct {
+		v   interface{}
+		err string
+	}{
+		{"product", "ObjectFromStruct: string is not a struct"},
+		{badTag{}, `badTag.id: invalid gofed tag option "keys"`},
+		{fieldKey{}, `fieldKey.id: invalid gofed tag option "key=id"`},
+		{badType{}, "badType.lookup: unsupported Go type map[string]string"},
+	}
+	for _, test := range tests {
+		if _, err := NewFederation().ObjectFromStruct(test.v); err == nil || err.Error() != test.err {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+}
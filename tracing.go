@@ -0,0 +1,277 @@
+package gofed
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// TraceHeader is the header the router sends, with the value "ftv1", to ask
+// a subgraph for a federated trace of the request
+const TraceHeader = "apollo-federation-include-trace"
+
+// ftv1 is the TraceHeader value, and the response extension holding the trace
+const ftv1 = "ftv1"
+
+type traceContextKey struct{}
+
+// traceRecorder collects the timings of the fields resolved by a request,
+// nodes are keyed by the response path of their field
+type traceRecorder struct {
+	mu    sync.Mutex
+	start time.Time
+	root  *traceNode
+	nodes map[string]*traceNode
+}
+
+// traceNode is a Trace.Node, either a field named by responseName or a list
+// item with its index
+type traceNode struct {
+	responseName      string
+	index             int
+	isIndex           bool
+	originalFieldName string
+	typeName          string
+	parentType        string
+	startTime         time.Duration
+	endTime           time.Duration
+	errors            []gqlerrors.FormattedError
+	children          []*traceNode
+}
+
+func newTraceRecorder() *traceRecorder {
+	return &traceRecorder{
+		start: time.Now(),
+		root:  &traceNode{},
+		nodes: make(map[string]*traceNode),
+	}
+}
+
+func withTraceRecorder(ctx context.Context, trace *traceRecorder) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace)
+}
+
+func traceRecorderFrom(ctx context.Context) *traceRecorder {
+	// graphql.Do leaves the context nil when Params has none
+	if ctx == nil {
+		return nil
+	}
+	trace, _ := ctx.Value(traceContextKey{}).(*traceRecorder)
+	return trace
+}
+
+// node returns the node of a response path, adding it and the list item
+// nodes above it when they are missing
+func (t *traceRecorder) node(path []interface{}) *traceNode {
+	if len(path) == 0 {
+		return t.root
+	}
+	key, _ := json.Marshal(path)
+	if node, ok := t.nodes[string(key)]; ok {
+		return node
+	}
+	parent := t.node(path[:len(path)-1])
+	node := &traceNode{}
+	switch k := path[len(path)-1].(type) {
+	case int:
+		node.index, node.isIndex = k, true
+	case string:
+		node.responseName = k
+	}
+	parent.children = append(parent.children, node)
+	t.nodes[string(key)] = node
+	return node
+}
+
+// startField adds the node of a field about to be resolved
+func (t *traceRecorder) startField(info *graphql.ResolveInfo) *traceNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	node := t.node(info.Path.AsArray())
+	if node.responseName != info.FieldName {
+		node.originalFieldName = info.FieldName
+	}
+	if info.ReturnType != nil {
+		node.typeName = info.ReturnType.String()
+	}
+	if info.ParentType != nil {
+		node.parentType = info.ParentType.Name()
+	}
+	node.startTime = time.Since(t.start)
+	return node
+}
+
+func (t *traceRecorder) endField(node *traceNode) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	node.endTime = time.Since(t.start)
+}
+
+// encode attaches the errors of result to the nodes of their path and
+// returns the base64 protobuf Trace sent in extensions.ftv1
+func (t *traceRecorder) encode(result *graphql.Result) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	end := time.Now()
+	for _, err := range result.Errors {
+		node := t.root
+		if len(err.Path) > 0 {
+			node = t.node(err.Path)
+		}
+		node.errors = append(node.errors, err)
+	}
+
+	var trace protoBuffer
+	trace.message(3, timestamp(end))
+	trace.message(4, timestamp(t.start))
+	trace.uint(11, uint64(end.Sub(t.start)))
+	trace.message(14, t.root.encode())
+	return base64.StdEncoding.EncodeToString(trace)
+}
+
+func (n *traceNode) encode() protoBuffer {
+	var node protoBuffer
+	if n.isIndex {
+		// index is part of a oneof, it is set even when 0
+		node.tag(2, wireVarint)
+		node.varint(uint64(n.index))
+	} else {
+		node.string(1, n.responseName)
+	}
+	node.string(3, n.typeName)
+	node.uint(8, uint64(n.startTime))
+	node.uint(9, uint64(n.endTime))
+	for _, err := range n.errors {
+		node.message(11, encodeTraceError(err))
+	}
+	for _, child := range n.children {
+		node.message(12, child.encode())
+	}
+	node.string(13, n.parentType)
+	node.string(14, n.originalFieldName)
+	return node
+}
+
+func encodeTraceError(err gqlerrors.FormattedError) protoBuffer {
+	var e protoBuffer
+	e.string(1, err.Message)
+	for _, loc := range err.Locations {
+		var l protoBuffer
+		l.uint(1, uint64(loc.Line))
+		l.uint(2, uint64(loc.Column))
+		e.message(2, l)
+	}
+	if data, jsonErr := json.Marshal(err); jsonErr == nil {
+		e.string(4, string(data))
+	}
+	return e
+}
+
+// timestamp encodes a google.protobuf.Timestamp
+func timestamp(t time.Time) protoBuffer {
+	var ts protoBuffer
+	ts.uint(1, uint64(t.Unix()))
+	ts.uint(2, uint64(t.Nanosecond()))
+	return ts
+}
+
+// protobuf wire types
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// protoBuffer writes the few protobuf wire types a Trace needs, fields with
+// a zero value are left out as proto3 does
+type protoBuffer []byte
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *protoBuffer) tag(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireVarint)
+	b.varint(v)
+}
+
+func (b *protoBuffer) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(s)))
+	*b = append(*b, s...)
+}
+
+func (b *protoBuffer) message(field int, m protoBuffer) {
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(m)))
+	*b = append(*b, m...)
+}
+
+// tracingExtension records field timings for requests carrying a
+// traceRecorder in their context, set by Handler when the router asks for a
+// trace. Other requests go through untouched.
+type tracingExtension struct{}
+
+var _ graphql.Extension = tracingExtension{}
+
+func (tracingExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (tracingExtension) Name() string {
+	return ftv1
+}
+
+func (tracingExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (tracingExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (tracingExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (tracingExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	trace := traceRecorderFrom(ctx)
+	if trace == nil {
+		return ctx, func(interface{}, error) {}
+	}
+	node := trace.startField(info)
+	return ctx, func(interface{}, error) {
+		trace.endField(node)
+	}
+}
+
+// HasResult is false, Handler attaches the trace as only it knows whether
+// the router asked for one
+func (tracingExtension) HasResult() bool {
+	return false
+}
+
+func (tracingExtension) GetResult(context.Context) interface{} {
+	return nil
+}
@@ -0,0 +1,178 @@
+package gofed
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+// protoMessage is a decoded protobuf message, the values of each field number
+// are uint64 for varints and []byte for length-delimited fields
+type protoMessage map[int][]interface{}
+
+func decodeProto(t *testing.T, data []byte) protoMessage {
+	m := make(protoMessage)
+	readVarint := func() uint64 {
+		var v uint64
+		for shift := 0; ; shift += 7 {
+			if len(data) == 0 {
+				t.Fatal("truncated varint")
+			}
+			b := data[0]
+			data = data[1:]
+			v |= uint64(b&0x7f) << shift
+			if b < 0x80 {
+				return v
+			}
+		}
+	}
+	for len(data) > 0 {
+		key := readVarint()
+		field := int(key >> 3)
+		switch key & 7 {
+		case wireVarint:
+			m[field] = append(m[field], readVarint())
+		case wireBytes:
+			n := readVarint()
+			if uint64(len(data)) < n {
+				t.Fatal("truncated field")
+			}
+			m[field] = append(m[field], data[:n])
+			data = data[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return m
+}
+
+func (m protoMessage) string(field int) string {
+	if len(m[field]) == 0 {
+		return ""
+	}
+	return string(m[field][0].([]byte))
+}
+
+func (m protoMessage) uint(field int) uint64 {
+	if len(m[field]) == 0 {
+		return 0
+	}
+	return m[field][0].(uint64)
+}
+
+// child returns the child node of a Trace.Node with the response name or index
+func (m protoMessage) child(t *testing.T, name interface{}) protoMessage {
+	for _, c := range m[12] {
+		child := decodeProto(t, c.([]byte))
+		if index, ok := name.(int); ok && len(child[2]) > 0 && child.uint(2) == uint64(index) {
+			return child
+		}
+		if child.string(1) == name {
+			return child
+		}
+	}
+	t.Fatalf("node has no child %v", name)
+	return nil
+}
+
+func buildTracingFederation() *Federation {
+
+	itemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"price": &graphql.Field{
+				Type: graphql.Float,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return nil, errors.New("price unavailable")
+				},
+			},
+		},
+	})
+
+	fed := NewFederation()
+	fed.BuildSubgraphSchema(graphql.Fields{
+		"items": &graphql.Field{
+			Type: graphql.NewList(itemType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return []map[string]interface{}{{"name": "a"}, {"name": "b"}}, nil
+			},
+		},
+	}, nil)
+	return fed
+}
+
+func TestTracing(t *testing.T) {
+
+	fed := buildTracingFederation()
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ items { title: name price } }"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(TraceHeader, "ftv1")
+	w := httptest.NewRecorder()
+	fed.Handler().ServeHTTP(w, r)
+
+	var response struct {
+		Extensions struct {
+			FTV1 string `json:"ftv1"`
+		} `json:"extensions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("unexpected response %s: %s", w.Body, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(response.Extensions.FTV1)
+	if err != nil || len(data) == 0 {
+		t.Fatalf("extensions.ftv1 is not a base64 trace: %q", response.Extensions.FTV1)
+	}
+
+	trace := decodeProto(t, data)
+	start := decodeProto(t, trace[4][0].([]byte))
+	end := decodeProto(t, trace[3][0].([]byte))
+	if start.uint(1) == 0 || end.uint(1) < start.uint(1) || trace.uint(11) == 0 {
+		t.Errorf("unexpected trace times: start %v, end %v, duration %d", start, end, trace.uint(11))
+	}
+
+	root := decodeProto(t, trace[14][0].([]byte))
+	items := root.child(t, "items")
+	if items.string(3) != "[Item]" || items.string(13) != "Query" {
+		t.Errorf("unexpected items node type %q, parent type %q", items.string(3), items.string(13))
+	}
+	if items.uint(9) < items.uint(8) {
+		t.Errorf("items ended at %d, before it started at %d", items.uint(9), items.uint(8))
+	}
+
+	// list items are index nodes, the first one has index 0
+	first := items.child(t, 0)
+	title := first.child(t, "title")
+	if title.string(14) != "name" || title.string(3) != "String" || title.string(13) != "Item" {
+		t.Errorf("unexpected aliased node: original name %q, type %q, parent type %q", title.string(14), title.string(3), title.string(13))
+	}
+
+	price := items.child(t, 1).child(t, "price")
+	if len(price[11]) != 1 {
+		t.Fatalf("expected an error on items.1.price, got %d", len(price[11]))
+	}
+	traceErr := decodeProto(t, price[11][0].([]byte))
+	if traceErr.string(1) != "price unavailable" || len(traceErr[2]) != 1 || !strings.Contains(traceErr.string(4), `"path":["items",1,"price"]`) {
+		t.Errorf("unexpected trace error: message %q, json %q", traceErr.string(1), traceErr.string(4))
+	}
+}
+
+func TestTracingNotRequested(t *testing.T) {
+
+	fed := buildTracingFederation()
+	w := serveRequest(fed, "POST", "/graphql", "application/json", "", `{"query": "{ items { name } }"}`)
+	if body := strings.TrimSpace(w.Body.String()); body != `{"data":{"items":[{"name":"a"},{"name":"b"}]}}` {
+		t.Errorf("unexpected response %s", body)
+	}
+}
@@ -0,0 +1,207 @@
+package gofed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// typedEntityResolver resolves the representations of a single entity type
+type typedEntityResolver interface {
+	// resolve returns an entity, or an error, for each representation
+	resolve(ctx context.Context, reps []*Representation) []interface{}
+	// validate checks the key and entity types against the entity
+	validate(typeName string, fields graphql.FieldDefinitionMap, keys []string) error
+}
+
+type entityResolverFor[K, T any] struct {
+	resolveFn func(ctx context.Context, keys []K) ([]T, error)
+}
+
+// RegisterEntityResolver resolves the representations of the entity typeName
+// with a typed batch resolver, instead of the resolvers set with
+// SetEntityResolver and SetEntityBatchResolver. Representations are decoded
+// into K with encoding/json, so its fields are matched to the key fields by
+// their json tags. The resolver returns an entity for each key, in order, a
+// nil entity resolves to null. BuildSubgraphSchema checks that K holds the
+// fields of a @key and that T, when it is a struct, has a Go field for every
+// field of the entity without its own resolver.
+func RegisterEntityResolver[K, T any](f *Federation, typeName string, resolve func(ctx context.Context, keys []K) ([]T, error)) {
+	if f.typedResolvers == nil {
+		f.typedResolvers = make(map[string]typedEntityResolver)
+	}
+	f.typedResolvers[typeName] = &entityResolverFor[K, T]{resolveFn: resolve}
+}
+
+func (r *entityResolverFor[K, T]) resolve(ctx context.Context, reps []*Representation) []interface{} {
+	resolved := make([]interface{}, len(reps))
+	keys := make([]K, 0, len(reps))
+	positions := make([]int, 0, len(reps))
+	for i, rep := range reps {
+		var key K
+		if err := decodeRepresentation(rep, &key); err != nil {
+			resolved[i] = err
+			continue
+		}
+		keys = append(keys, key)
+		positions = append(positions, i)
+	}
+	if len(keys) == 0 {
+		return resolved
+	}
+
+	entities, err := r.resolveFn(ctx, keys)
+	if err == nil && len(entities) != len(keys) {
+		err = fmt.Errorf("entity resolver returned %d entities for %d keys", len(entities), len(keys))
+	}
+	for j, i := range positions {
+		if err != nil {
+			resolved[i] = err
+			continue
+		}
+		if entity := reflect.ValueOf(&entities[j]).Elem(); isNilValue(entity) {
+			continue
+		}
+		resolved[i] = entities[j]
+	}
+	return resolved
+}
+
+// decodeRepresentation decodes the values of a representation into a key struct
+func decodeRepresentation(rep *Representation, key interface{}) error {
+	b, err := json.Marshal(rep.Values)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRepresentation, err)
+	}
+	if err := json.Unmarshal(b, key); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRepresentation, err)
+	}
+	return nil
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func (r *entityResolverFor[K, T]) validate(typeName string, fields graphql.FieldDefinitionMap, keys []string) error {
+	keyType := reflect.TypeOf((*K)(nil)).Elem()
+	if keyType.Kind() != reflect.Struct {
+		return fmt.Errorf("%s: key type %s must be a struct", typeName, keyType)
+	}
+
+	// every field of K is a field of the entity, and K holds every field of a key
+	keyFields := jsonFieldNames(keyType)
+	for _, name := range keyFields {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("%s: field %s of key type %s is not defined on %s", typeName, name, keyType, typeName)
+		}
+	}
+	matched := false
+	for _, key := range keys {
+		missing := false
+		for _, field := range topLevelKeyFields(key) {
+			if !containsString(keyFields, field) {
+				missing = true
+			}
+		}
+		if !missing {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return fmt.Errorf("%s: key type %s doesn't hold the fields of any @key", typeName, keyType)
+	}
+
+	entityType := reflect.TypeOf((*T)(nil)).Elem()
+	for entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	if entityType.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, field := range sortFields(fields) {
+		if field.Resolve != nil {
+			continue
+		}
+		if !hasGoField(entityType, field.Name) {
+			return fmt.Errorf("%s.%s: entity type %s has no matching field", typeName, field.Name, entityType)
+		}
+	}
+	return nil
+}
+
+// jsonFieldNames returns the names encoding/json uses for the fields of a struct
+func jsonFieldNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// hasGoField reports if the graphql-go default resolver finds a struct field for name
+func hasGoField(t reflect.Type, name string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.EqualFold(field.Name, name) {
+			return true
+		}
+		for _, tag := range []string{"json", "graphql"} {
+			if strings.Split(field.Tag.Get(tag), ",")[0] == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateTypedResolvers checks every typed resolver is registered for an
+// entity resolvable by this subgraph and matches its fields
+func (f *Federation) validateTypedResolvers() error {
+	names := make([]string, 0, len(f.typedResolvers))
+	for name := range f.typedResolvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var fields graphql.FieldDefinitionMap
+		var extensions map[string]interface{}
+		if obj := f.entityObject(name); obj != nil {
+			fields, extensions = obj.Fields(), obj.Extensions()
+		} else if iface := f.entityInterface(name); iface != nil {
+			fields, extensions = iface.Fields(), iface.Extensions()
+		} else {
+			return fmt.Errorf("%s: entity resolver registered for a type that isn't a resolvable entity", name)
+		}
+		keys, err := resolvableKeyDirectives(extensions)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		if err := f.typedResolvers[name].validate(name, fields, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package gofed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+type storeKey struct {
+	ID string `json:"id"`
+}
+
+type storeRegion struct {
+	Code string `json:"code"`
+}
+
+type store struct {
+	ID     string       `json:"id"`
+	Number int          `json:"number"`
+	Rating float64      `json:"rating"`
+	Region *storeRegion `json:"region"`
+}
+
+func TestRegisterEntityResolver(t *testing.T) {
+
+	var reps []*Representation
+	var keys []storeKey
+	fed := buildRepresentationFederation(&reps, func(fed *Federation) {
+		RegisterEntityResolver(fed, "Store", func(ctx context.Context, batch []storeKey) ([]*store, error) {
+			keys = append(keys, batch...)
+			stores := make([]*store, 0, len(batch))
+			for _, key := range batch {
+				if key.ID == "0" {
+					stores = append(stores, nil)
+					continue
+				}
+				stores = append(stores, &store{ID: key.ID, Number: 1, Region: &storeRegion{Code: "EU"}})
+			}
+			return stores, nil
+		})
+	})
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+
+	r := graphql.Do(graphql.Params{
+		Schema:        *fed.Schema(),
+		RequestString: `query ($r: [_Any!]!) { _entities(representations: $r) { ... on Store { id region { code } } } }`,
+		VariableValues: map[string]interface{}{
+			"r": []interface{}{
+				map[string]interface{}{"__typename": "Store", "id": float64(7)},
+				map[string]interface{}{"__typename": "Store", "id": "0"},
+				map[string]interface{}{"__typename": "Store", "id": "8"},
+			},
+		},
+	})
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	data, _ := json.Marshal(r.Data)
+	if string(data) != `{"_entities":[{"id":"7","region":{"code":"EU"}},null,{"id":"8","region":{"code":"EU"}}]}` {
+		t.Errorf("unexpected data %s", data)
+	}
+	if fmt.Sprint(keys) != "[{7} {0} {8}]" {
+		t.Errorf("representations not decoded into keys: %v", keys)
+	}
+	if len(reps) != 0 {
+		t.Errorf("the untyped entity resolver should not be called")
+	}
+}
+
+func TestRegisterEntityResolverValidation(t *testing.T) {
+
+	type unknownKey struct {
+		Code string `json:"code"`
+	}
+	type partialKey struct {
+		Number int `json:"number"`
+	}
+	type partialStore struct {
+		ID string `json:"id"`
+	}
+	noop := func(ctx context.Context, keys []storeKey) ([]*partialStore, error) { return nil, nil }
+
+	tests := []struct {
+		register func(fed *Federation)
+		err      string
+	}{
+		{
+			func(fed *Federation) {
+				RegisterEntityResolver(fed, "Store", func(ctx context.Context, keys []unknownKey) ([]store, error) { return nil, nil })
+			},
+			"Store: field code of key type gofed.unknownKey is not defined on Store",
+		},
+		{
+			func(fed *Federation) {
+				RegisterEntityResolver(fed, "Store", func(ctx context.Context, keys []partialKey) ([]store, error) { return nil, nil })
+			},
+			"Store: key type gofed.partialKey doesn't hold the fields of any @key",
+		},
+		{
+			func(fed *Federation) {
+				RegisterEntityResolver(fed, "Store", noop)
+			},
+			"Store.number: entity type gofed.partialStore has no matching field",
+		},
+		{
+			func(fed *Federation) {
+				RegisterEntityResolver(fed, "Region", func(ctx context.Context, keys []storeKey) ([]store, error) { return nil, nil })
+			},
+			"Region: entity resolver registered for a type that isn't a resolvable entity",
+		},
+	}
+	for _, test := range tests {
+		var reps []*Representation
+		fed := buildRepresentationFederation(&reps, test.register)
+		if err := fed.Error(); err == nil || err.Error() != test.err {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+}
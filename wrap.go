@@ -0,0 +1,257 @@
+package gofed
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// WrapSchema builds the subgraph schema from an existing graphql-go schema,
+// adding _entities, _service, _Any and the _Entity union. Every type,
+// custom directive and root field of schema is kept, the root types are
+// named Query, Mutation and Subscription.
+//
+// directives attaches federation directives to types and fields without
+// touching their extensions, keyed by "Type" or "Type.field":
+//
+//	fed.WrapSchema(&schema, map[string][]*gofed.DirectiveValue{
+//		"Product":     {gofed.Key("upc")},
+//		"Product.sku": {gofed.Shareable()},
+//	})
+//
+// They are added to the directives the extensions already hold. Objects,
+// interfaces and unions are rebuilt to carry them, scalars, enums and input
+// types are reused.
+func (f *Federation) WrapSchema(schema *graphql.Schema, directives map[string][]*DirectiveValue) *graphql.Schema {
+	if schema == nil || schema.QueryType() == nil {
+		f.err = fmt.Errorf("WrapSchema: schema has no query type")
+		return nil
+	}
+	if err := checkDirectiveTargets(schema, directives); err != nil {
+		f.err = err
+		return nil
+	}
+
+	w := &schemaWrapper{
+		directives: directives,
+		objects:    make(map[string]*graphql.Object),
+		interfaces: make(map[string]*graphql.Interface),
+		unions:     make(map[string]*graphql.Union),
+	}
+
+	roots := make(map[string]bool)
+	for _, root := range []*graphql.Object{schema.QueryType(), schema.MutationType(), schema.SubscriptionType()} {
+		if root != nil {
+			roots[root.Name()] = true
+		}
+	}
+	names := make([]string, 0, len(schema.TypeMap()))
+	for name := range schema.TypeMap() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch name {
+		case "String", "Int", "Float", "Boolean", "ID":
+			continue
+		}
+		if roots[name] || isFederationTypeName(name) {
+			continue
+		}
+		f.types = append(f.types, w.namedType(schema.TypeMap()[name]))
+	}
+
+	for _, d := range schema.Directives() {
+		if !isFederationDirectiveName(d.Name) {
+			f.AddDirective(d)
+		}
+	}
+
+	queryFields := w.fields(schema.QueryType().Name(), schema.QueryType().Fields())
+	delete(queryFields, "_entities")
+	delete(queryFields, "_service")
+	var mutationFields graphql.Fields
+	if mutation := schema.MutationType(); mutation != nil {
+		mutationFields = w.fields(mutation.Name(), mutation.Fields())
+	}
+	var subscriptionType *graphql.Object
+	if subscription := schema.SubscriptionType(); subscription != nil {
+		subscriptionType = graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Subscription",
+			Fields: w.fields(subscription.Name(), subscription.Fields()),
+		})
+	}
+	return f.buildSchema(queryFields, mutationFields, subscriptionType)
+}
+
+// checkDirectiveTargets checks every key of a WrapSchema directives map names
+// an object or interface type, or one of its fields
+func checkDirectiveTargets(schema *graphql.Schema, directives map[string][]*DirectiveValue) error {
+	paths := make([]string, 0, len(directives))
+	for path := range directives {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		typeName, fieldName, hasField := strings.Cut(path, ".")
+		var fields graphql.FieldDefinitionMap
+		switch t := schema.Type(typeName).(type) {
+		case *graphql.Object:
+			fields = t.Fields()
+		case *graphql.Interface:
+			fields = t.Fields()
+		default:
+			return fmt.Errorf("WrapSchema: directives for %q: %s is not an object or interface type", path, typeName)
+		}
+		if _, ok := fields[fieldName]; hasField && !ok {
+			return fmt.Errorf("WrapSchema: directives for %q: %s has no field %s", path, typeName, fieldName)
+		}
+	}
+	return nil
+}
+
+// schemaWrapper rebuilds the object, interface and union types of a schema
+// with the directives of a WrapSchema directives map
+type schemaWrapper struct {
+	directives map[string][]*DirectiveValue
+	objects    map[string]*graphql.Object
+	interfaces map[string]*graphql.Interface
+	unions     map[string]*graphql.Union
+}
+
+// namedType returns the rebuilt type of a named type, types that can't
+// reference objects are returned as they are
+func (w *schemaWrapper) namedType(t graphql.Type) graphql.Type {
+	switch t := t.(type) {
+	case *graphql.Object:
+		return w.object(t)
+	case *graphql.Interface:
+		return w.iface(t)
+	case *graphql.Union:
+		return w.union(t)
+	}
+	return t
+}
+
+func (w *schemaWrapper) typeRef(t graphql.Type) graphql.Type {
+	switch t := t.(type) {
+	case *graphql.List:
+		return graphql.NewList(w.typeRef(t.OfType))
+	case *graphql.NonNull:
+		return graphql.NewNonNull(w.typeRef(t.OfType))
+	}
+	return w.namedType(t)
+}
+
+func (w *schemaWrapper) object(t *graphql.Object) *graphql.Object {
+	if obj, ok := w.objects[t.Name()]; ok {
+		return obj
+	}
+	obj := graphql.NewObject(graphql.ObjectConfig{
+		Name:        t.Name(),
+		Description: t.PrivateDescription,
+		Extensions:  w.extensions(t.Name(), t.Extensions()),
+		IsTypeOf:    t.IsTypeOf,
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return w.fields(t.Name(), t.Fields())
+		}),
+		Interfaces: (graphql.InterfacesThunk)(func() []*graphql.Interface {
+			interfaces := make([]*graphql.Interface, 0, len(t.Interfaces()))
+			for _, iface := range t.Interfaces() {
+				interfaces = append(interfaces, w.iface(iface))
+			}
+			return interfaces
+		}),
+	})
+	w.objects[t.Name()] = obj
+	return obj
+}
+
+func (w *schemaWrapper) iface(t *graphql.Interface) *graphql.Interface {
+	if iface, ok := w.interfaces[t.Name()]; ok {
+		return iface
+	}
+	iface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Extensions:  w.extensions(t.Name(), t.Extensions()),
+		ResolveType: w.resolveType(t.ResolveType),
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return w.fields(t.Name(), t.Fields())
+		}),
+	})
+	w.interfaces[t.Name()] = iface
+	return iface
+}
+
+func (w *schemaWrapper) union(t *graphql.Union) *graphql.Union {
+	if union, ok := w.unions[t.Name()]; ok {
+		return union
+	}
+	members := make([]*graphql.Object, 0, len(t.Types()))
+	for _, member := range t.Types() {
+		members = append(members, w.object(member))
+	}
+	union := graphql.NewUnion(graphql.UnionConfig{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Extensions:  t.Extensions(),
+		Types:       members,
+		ResolveType: w.resolveType(t.ResolveType),
+	})
+	w.unions[t.Name()] = union
+	return union
+}
+
+// resolveType maps the objects returned by the original ResolveType of an
+// interface or union to their rebuilt types
+func (w *schemaWrapper) resolveType(resolveType graphql.ResolveTypeFn) graphql.ResolveTypeFn {
+	if resolveType == nil {
+		return nil
+	}
+	return func(p graphql.ResolveTypeParams) *graphql.Object {
+		obj := resolveType(p)
+		if obj == nil {
+			return nil
+		}
+		return w.object(obj)
+	}
+}
+
+// fields turns field definitions back into field configs on the rebuilt types
+func (w *schemaWrapper) fields(typeName string, definitions graphql.FieldDefinitionMap) graphql.Fields {
+	fields := make(graphql.Fields, len(definitions))
+	for name, def := range definitions {
+		args := make(graphql.FieldConfigArgument, len(def.Args))
+		for _, arg := range def.Args {
+			args[arg.Name()] = &graphql.ArgumentConfig{
+				Type:         arg.Type,
+				DefaultValue: arg.DefaultValue,
+				Description:  arg.PrivateDescription,
+				Extensions:   arg.Extensions,
+			}
+		}
+		fields[name] = &graphql.Field{
+			Name:              name,
+			Type:              w.typeRef(def.Type).(graphql.Output),
+			Args:              args,
+			Resolve:           def.Resolve,
+			Subscribe:         def.Subscribe,
+			Description:       def.Description,
+			DeprecationReason: def.DeprecationReason,
+			Extensions:        w.extensions(typeName+"."+name, def.Extensions),
+		}
+	}
+	return fields
+}
+
+// extensions merges the directives registered for path into extensions
+func (w *schemaWrapper) extensions(path string, extensions map[string]interface{}) map[string]interface{} {
+	if directives := w.directives[path]; len(directives) > 0 {
+		return MergeDirectives(extensions, directives...)
+	}
+	return extensions
+}
@@ -0,0 +1,198 @@
+package gofed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+type testProduct struct {
+	UPC string `json:"upc"`
+	SKU string `json:"sku"`
+}
+
+type testReview struct {
+	ID   string `json:"id"`
+	Body string `json:"body"`
+}
+
+// buildExistingSchema builds a graphql-go schema the way a service not using
+// gofed would, only Review carries a @key in its extensions
+func buildExistingSchema(t *testing.T) graphql.Schema {
+
+	var productType, reviewType *graphql.Object
+
+	nodeInterface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		},
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			if _, ok := p.Value.(testReview); ok {
+				return reviewType
+			}
+			return nil
+		},
+	})
+
+	productType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Product",
+		Fields: graphql.Fields{
+			"upc": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"sku": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+	reviewType = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Review",
+		Interfaces: []*graphql.Interface{nodeInterface},
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"body": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+		Extensions: mediaKey(),
+	})
+
+	auditDirective := graphql.NewDirective(graphql.DirectiveConfig{
+		Name:      "audit",
+		Locations: []string{graphql.DirectiveLocationFieldDefinition},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"node": &graphql.Field{
+					Type: nodeInterface,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return testReview{ID: "r1", Body: "Great"}, nil
+					},
+				},
+				"topProduct": &graphql.Field{
+					Type: productType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return testProduct{UPC: "p1", SKU: "s1"}, nil
+					},
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "RootMutation",
+			Fields: graphql.Fields{
+				"deleteReview": &graphql.Field{
+					Type: graphql.Boolean,
+					Args: graphql.FieldConfigArgument{
+						"id": &graphql.ArgumentConfig{
+							Type: graphql.NewNonNull(graphql.ID),
+						},
+					},
+					Extensions: Directives(&DirectiveValue{Name: "audit"}),
+				},
+			},
+		}),
+		Types:      []graphql.Type{reviewType},
+		Directives: append(append([]*graphql.Directive{}, graphql.SpecifiedDirectives...), auditDirective),
+	})
+	if err != nil {
+		t.Fatalf("unexpected schema error: %s", err)
+	}
+	return schema
+}
+
+func TestWrapSchema(t *testing.T) {
+
+	existing := buildExistingSchema(t)
+
+	fed := NewFederation()
+	schema := fed.WrapSchema(&existing, map[string][]*DirectiveValue{
+		"Product":     {Key("upc")},
+		"Product.sku": {Shareable()},
+	})
+	if err := fed.Error(); err != nil {
+		t.Fatalf("unexpected build error: %s", err)
+	}
+	fed.SetEntityResolver(func(rep *Representation) (interface{}, error) {
+		if rep.TypeName == "Product" {
+			return testProduct{UPC: rep.KeyValue.(string), SKU: "s2"}, nil
+		}
+		return testReview{ID: rep.KeyValue.(string), Body: "Fine"}, nil
+	})
+
+	r := graphql.Do(graphql.Params{
+		Schema: *schema,
+		RequestString: `{
+			node { id ... on Review { body } }
+			topProduct { upc }
+			_entities(representations: [{__typename: "Product", upc: "p2"}, {__typename: "Review", id: "r2"}]) {
+				... on Product { upc sku }
+				... on Review { body }
+			}
+		}`,
+	})
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	data, _ := json.Marshal(r.Data)
+	if string(data) != `{"_entities":[{"sku":"s2","upc":"p2"},{"body":"Fine"}],"node":{"body":"Great","id":"r1"},"topProduct":{"upc":"p1"}}` {
+		t.Errorf("unexpected data %s", data)
+	}
+
+	if types := fed.entityType.Types(); len(types) != 2 {
+		t.Errorf("unexpected entity types %v", types)
+	}
+
+	sdl := fed.PrintSDL()
+	for _, line := range []string{
+		`directive @audit on FIELD_DEFINITION`,
+		`type Product @key(fields: "upc") {`,
+		`  sku: String @shareable`,
+		`type Review implements Node @key(fields: "id") {`,
+		`type Mutation {`,
+		`  deleteReview(id: ID!): Boolean @audit`,
+	} {
+		if !strings.Contains(sdl, line+"\n") {
+			t.Errorf("sdl is missing line %q:\n%s", line, sdl)
+		}
+	}
+
+	// the wrapped schema is left unchanged
+	if obj := existing.Type("Product").(*graphql.Object); len(obj.Extensions()) > 0 {
+		t.Errorf("WrapSchema changed the extensions of the existing schema: %v", obj.Extensions())
+	}
+}
+
+func TestWrapSchemaErrors(t *testing.T) {
+
+	existing := buildExistingSchema(t)
+	tests := []struct {
+		directives map[string][]*DirectiveValue
+		err        string
+	}{
+		{map[string][]*DirectiveValue{"Prodcut": {Key("upc")}}, `WrapSchema: directives for "Prodcut": Prodcut is not an object or interface type`},
+		{map[string][]*DirectiveValue{"Product.price": {External()}}, `WrapSchema: directives for "Product.price": Product has no field price`},
+		{map[string][]*DirectiveValue{"Product": {Key("id")}}, `Product.id: field is not defined on Product`},
+	}
+	for _, test := range tests {
+		fed := NewFederation()
+		fed.WrapSchema(&existing, test.directives)
+		if err := fed.Error(); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+
+	fed := NewFederation()
+	if fed.WrapSchema(nil, nil); fed.Error() == nil {
+		t.Error("expected an error wrapping a nil schema")
+	}
+}